@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"fmt"
 	"github.com/chnyangzhen/kago-fly/pkg/config"
+	_ "github.com/chnyangzhen/kago-fly/pkg/config/apollo"
 	"github.com/chnyangzhen/kago-fly/pkg/constant"
 	"github.com/chnyangzhen/kago-fly/pkg/logger"
 	"github.com/chnyangzhen/kago-fly/pkg/server"
+	"github.com/spf13/cast"
 	"github.com/urfave/cli/v2"
 	"os"
 	"sort"
@@ -42,9 +45,10 @@ func Go(app App, l ...server.Lifecycle) {
 			RunApplication(app.Banner),
 		),
 	)
-	err := newApp.Run(os.Args)
-	if err != nil {
-		panic(any(err))
+	// 启动失败（如配置校验失败）时输出错误并以非0状态退出
+	if err := newApp.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
 
@@ -67,6 +71,36 @@ func NewApp(app App, action cli.ActionFunc) *cli.App {
 				Usage:   "application config names",               // 功能描述
 			},
 
+			// 配置目录，可重复指定，按顺序合并；环境变量配置时使用逗号分隔，如：CONFIG_DIRS=./conf.d,/etc/kago
+			&cli.StringSliceFlag{
+				Name:    constant.ConfigDir,
+				Aliases: []string{"config-dir"},
+				Value:   cli.NewStringSlice(constant.DefaultConfigPath),
+				EnvVars: []string{constant.EnvConfigDirs},
+				Usage:   "config directories, merged in order",
+			},
+
+			// 显式指定的配置文件，可重复指定，在配置目录之后按顺序合并
+			&cli.StringSliceFlag{
+				Name:    constant.ConfigFile,
+				Aliases: []string{"config-file"},
+				EnvVars: []string{constant.EnvConfigFiles},
+				Usage:   "config files, merged in order after config directories",
+			},
+
+			// 覆盖任意配置，可重复指定，如：--set listeners.web.port=8080；优先级高于环境变量与配置文件
+			&cli.StringSliceFlag{
+				Name:  constant.Set,
+				Usage: "override config key, key=value",
+			},
+
+			// 激活的Profile，多个Profile使用逗号分隔，如：APP_PROFILE=dev,local
+			&cli.StringSliceFlag{
+				Name:    constant.Profile,
+				EnvVars: []string{constant.EnvProfile},
+				Usage:   "active profiles, merge {name}-{profile} config files after base config files",
+			},
+
 			&cli.StringFlag{
 				Name:    constant.LogConfigName,                  // 参数名称
 				Value:   constant.DefaultLogConfigName,           // 参数默认值
@@ -74,6 +108,10 @@ func NewApp(app App, action cli.ActionFunc) *cli.App {
 				Usage:   "Load logger configuration from `FILE`", // 功能描述
 			},
 		},
+		// 子命令
+		Commands: []*cli.Command{
+			ConfigCommand(),
+		},
 		// 该程序执行的代码（未指定子命令时执行的操作），参考02:https://www.cnblogs.com/wangjq19920210/p/15352101.html
 		Action: action,
 	}
@@ -99,19 +137,38 @@ func NewActions(actions ...cli.ActionFunc) cli.ActionFunc {
 // InitViperComponent 用于初始化Viper组件
 func InitViperComponent() cli.ActionFunc {
 	return func(context *cli.Context) error {
-		if err := config.InitConfig(context.StringSlice(constant.ConfigNames)); err != nil {
+		if err := initConfig(context); err != nil {
 			return err
 		}
+		// 默认开启配置文件热加载，config.hot_reload=false时关闭
+		if cast.ToBool(config.GetWrapper(constant.ConfigConfig).GetOrDefault("hot_reload", true)) {
+			return config.Watch()
+		}
 		return nil
 	}
 }
 
+// initConfig 按启动参数加载配置，子命令中读取的是应用级别的启动参数
+func initConfig(context *cli.Context) error {
+	return config.InitConfig(context.StringSlice(constant.ConfigNames),
+		config.WithProfiles(context.StringSlice(constant.Profile)...),
+		config.WithConfigDirs(context.StringSlice(constant.ConfigDir)...),
+		config.WithConfigFiles(context.StringSlice(constant.ConfigFile)...),
+		config.WithSets(context.StringSlice(constant.Set)...),
+	)
+}
+
 // InitZapLoggerComponent 用于初始化Zap日志组件
 func InitZapLoggerComponent() cli.ActionFunc {
 	return func(context *cli.Context) error {
 		if err := logger.InitLogger(context.String(constant.LogConfigName)); err != nil {
 			return err
 		}
+		config.SetLogger(logger.GetLogger())
+		// 与配置文件一致，开启热加载时日志配置文件变更后更新日志级别
+		if cast.ToBool(config.GetWrapper(constant.ConfigConfig).GetOrDefault("hot_reload", true)) {
+			return logger.Watch(context.String(constant.LogConfigName))
+		}
 		return nil
 	}
 }
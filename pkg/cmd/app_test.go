@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/urfave/cli/v2"
+	"os"
+	"testing"
+)
+
+func TestFlagNamesAndAliases(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		flag string
+		want []string
+	}{
+		{"config_dir", []string{"--config_dir", "a", "--config_dir", "b"}, constant.ConfigDir, []string{"a", "b"}},
+		{"config-dir alias", []string{"--config-dir", "a", "--config-dir", "b"}, constant.ConfigDir, []string{"a", "b"}},
+		{"config_dir default", nil, constant.ConfigDir, []string{constant.DefaultConfigPath}},
+		{"config_file", []string{"--config_file", "app.yml"}, constant.ConfigFile, []string{"app.yml"}},
+		{"config-file alias", []string{"--config-file", "app.yml"}, constant.ConfigFile, []string{"app.yml"}},
+		{"profile", []string{"--profile", "dev", "--profile", "local"}, constant.Profile, []string{"dev", "local"}},
+		{"set", []string{"--set", "a.b=1"}, constant.Set, []string{"a.b=1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{constant.EnvConfigDirs, constant.EnvConfigFiles, constant.EnvProfile} {
+				t.Setenv(env, "")
+				os.Unsetenv(env)
+			}
+			var got []string
+			app := NewApp(_app, func(ctx *cli.Context) error {
+				got = ctx.StringSlice(tt.flag)
+				return nil
+			})
+			if err := app.Run(append([]string{"app"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("%s = %v, want %v", tt.flag, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("%s = %v, want %v", tt.flag, got, tt.want)
+				}
+			}
+		})
+	}
+}
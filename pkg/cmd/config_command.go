@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/urfave/cli/v2"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// ConfigCommand 配置相关的子命令：输出生效的配置，加密、解密配置值，生成密钥
+func ConfigCommand() *cli.Command {
+	keyFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "key",
+			Usage: "base64 secret key, default read from CONFIG_SECRET_KEY or CONFIG_SECRET_KEY_FILE",
+		},
+		&cli.StringFlag{
+			Name:    "key_file",
+			Aliases: []string{"key-file"},
+			Usage:   "secret key `FILE`",
+		},
+	}
+	return &cli.Command{
+		Name:  "config",
+		Usage: "config tools",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "encrypt",
+				Usage:     "encrypt a value to ENC(...), read from stdin when no argument is given",
+				ArgsUsage: "[value]",
+				Flags:     keyFlags,
+				Action: func(ctx *cli.Context) error {
+					key, err := secretKey(ctx)
+					if err != nil {
+						return err
+					}
+					value, err := argOrStdin(ctx)
+					if err != nil {
+						return err
+					}
+					encrypted, err := config.Encrypt(value, key)
+					if err != nil {
+						return err
+					}
+					fmt.Println(encrypted)
+					return nil
+				},
+			},
+			{
+				Name:      "decrypt",
+				Usage:     "decrypt an ENC(...) value, read from stdin when no argument is given",
+				ArgsUsage: "[value]",
+				Flags:     keyFlags,
+				Action: func(ctx *cli.Context) error {
+					key, err := secretKey(ctx)
+					if err != nil {
+						return err
+					}
+					value, err := argOrStdin(ctx)
+					if err != nil {
+						return err
+					}
+					decrypted, err := config.Decrypt(value, key)
+					if err != nil {
+						return err
+					}
+					fmt.Println(decrypted)
+					return nil
+				},
+			},
+			{
+				Name:      "print",
+				Usage:     "print the effective config with the source of each key, sensitive values are masked",
+				ArgsUsage: "[prefix]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "output format, text or json",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					if err := initConfig(ctx); err != nil {
+						return err
+					}
+					return printEntries(config.Effective(ctx.Args().First()), ctx.String("format"))
+				},
+			},
+			{
+				Name:  "keygen",
+				Usage: "generate a base64 AES-256 secret key",
+				Action: func(ctx *cli.Context) error {
+					key, err := config.GenerateSecretKey()
+					if err != nil {
+						return err
+					}
+					fmt.Println(key)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// printEntries 按格式输出配置项，text格式每行为：Key、值、来源
+func printEntries(entries []config.Entry, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	case "text":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		for _, entry := range entries {
+			value, ok := entry.Value.(string)
+			if !ok {
+				data, _ := json.Marshal(entry.Value)
+				value = string(data)
+			}
+			source := entry.Source
+			if entry.Origin != "" {
+				source += " " + entry.Origin
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Key, value, source)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown format %q, expected text or json", format)
+	}
+}
+
+// secretKey 命令行参数指定的密钥优先，其次读取环境变量
+func secretKey(ctx *cli.Context) ([]byte, error) {
+	if key := ctx.String("key"); key != "" {
+		return config.ParseSecretKey(key)
+	}
+	if file := ctx.String("key_file"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return config.ParseSecretKey(string(data))
+	}
+	return config.LoadSecretKey()
+}
+
+// argOrStdin 读取命令行参数，未指定时从标准输入读取一行，避免明文留在shell历史中
+func argOrStdin(ctx *cli.Context) (string, error) {
+	if ctx.NArg() > 0 {
+		return ctx.Args().First(), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no value given")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
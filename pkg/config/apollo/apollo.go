@@ -0,0 +1,363 @@
+package apollo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// initialNotificationID 首次长轮询的通知ID，服务端立即返回当前的通知ID
+	initialNotificationID = -1
+	initialBackoff        = time.Second
+	maxBackoff            = time.Minute
+)
+
+func init() {
+	config.RegisterSource(constant.ApolloConfig, func(c *config.Configuration) (config.Source, error) {
+		var opts Options
+		if err := c.Bind(&opts); err != nil {
+			return nil, err
+		}
+		if !opts.Enabled {
+			return nil, nil
+		}
+		return New(opts)
+	})
+}
+
+type (
+	// Options Apollo客户端配置，对应配置文件中的 apollo_config
+	Options struct {
+		Enabled bool   `json:"enabled"`
+		Server  string `json:"server"`
+		AppID   string `json:"app_id"`
+		Cluster string `json:"cluster" default:"default"`
+		// Namespaces 按顺序合并，后面的覆盖前面的。properties格式的Key使用"."分隔层级，
+		// 以 .yml、.yaml、.json 结尾的命名空间解析content
+		Namespaces []string `json:"namespaces" default:"application"`
+		// Secret 访问密钥，开启访问控制时配置，支持 ENC(...)
+		Secret string `json:"secret"`
+		// CacheDir 本地缓存目录，服务端不可用时使用缓存启动
+		CacheDir string `json:"cache_dir" default:"./.apollo"`
+		// IP 灰度发布使用的客户端IP
+		IP string `json:"ip"`
+		// Timeout 拉取配置的超时时间
+		Timeout time.Duration `json:"timeout" default:"5s"`
+		// PollTimeout 长轮询的超时时间，需要大于服务端的挂起时间（60s）
+		PollTimeout time.Duration `json:"poll_timeout" default:"90s"`
+	}
+
+	// Client Apollo配置源，实现 config.Source
+	Client struct {
+		opts Options
+		http *http.Client
+		poll *http.Client
+
+		mu     sync.Mutex
+		states map[string]*namespaceState
+	}
+
+	namespaceState struct {
+		ReleaseKey     string            `json:"releaseKey"`
+		Configurations map[string]string `json:"configurations"`
+		notificationID int64
+	}
+
+	configResponse struct {
+		AppID          string            `json:"appId"`
+		Cluster        string            `json:"cluster"`
+		NamespaceName  string            `json:"namespaceName"`
+		Configurations map[string]string `json:"configurations"`
+		ReleaseKey     string            `json:"releaseKey"`
+	}
+
+	notification struct {
+		NamespaceName  string `json:"namespaceName"`
+		NotificationID int64  `json:"notificationId"`
+	}
+)
+
+// New 创建Apollo配置源
+func New(opts Options) (*Client, error) {
+	if opts.Server == "" || opts.AppID == "" {
+		return nil, errors.New("apollo: server and app_id are required")
+	}
+	opts.Server = strings.TrimRight(opts.Server, "/")
+	states := make(map[string]*namespaceState)
+	for _, ns := range opts.Namespaces {
+		states[ns] = &namespaceState{notificationID: initialNotificationID}
+	}
+	return &Client{
+		opts:   opts,
+		http:   &http.Client{Timeout: opts.Timeout},
+		poll:   &http.Client{Timeout: opts.PollTimeout},
+		states: states,
+	}, nil
+}
+
+func (c *Client) Name() string {
+	return "apollo:" + c.opts.AppID
+}
+
+// Timeout 加载超时时间，每个命名空间的拉取超时为配置的timeout，实现 config.SourceTimeout
+func (c *Client) Timeout() time.Duration {
+	return c.opts.Timeout * time.Duration(len(c.opts.Namespaces))
+}
+
+// Load 按顺序拉取所有命名空间的配置。拉取失败时依次使用内存中的配置、本地缓存，都不存在时返回错误
+func (c *Client) Load(ctx context.Context) (map[string]interface{}, error) {
+	v := viper.New()
+	for _, ns := range c.opts.Namespaces {
+		configurations, err := c.fetch(ctx, ns)
+		if err != nil {
+			configurations, err = c.fallback(ns, err)
+			if err != nil {
+				return nil, err
+			}
+		}
+		data, err := decode(ns, configurations)
+		if err != nil {
+			return nil, err
+		}
+		if err := v.MergeConfigMap(data); err != nil {
+			return nil, fmt.Errorf("apollo: merge namespace %s: %w", ns, err)
+		}
+	}
+	return v.AllSettings(), nil
+}
+
+// Watch 通过长轮询监听所有命名空间的变更通知，请求失败时指数退避重试
+func (c *Client) Watch(ctx context.Context, onChange func()) error {
+	backoff := initialBackoff
+	for {
+		changed, err := c.waitNotifications(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			config.GetLogger().Warnf("apollo: poll notifications failed, retry in %s: %v", backoff, err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			continue
+		}
+		backoff = initialBackoff
+		if changed {
+			onChange()
+		}
+	}
+}
+
+// fetch 拉取命名空间的配置，releaseKey未变化时服务端返回304，使用内存中的配置
+func (c *Client) fetch(ctx context.Context, ns string) (map[string]string, error) {
+	c.mu.Lock()
+	state := c.states[ns]
+	releaseKey := state.ReleaseKey
+	c.mu.Unlock()
+
+	query := url.Values{}
+	if releaseKey != "" {
+		query.Set("releaseKey", releaseKey)
+	}
+	if c.opts.IP != "" {
+		query.Set("ip", c.opts.IP)
+	}
+	path := fmt.Sprintf("/configs/%s/%s/%s", url.PathEscape(c.opts.AppID), url.PathEscape(c.opts.Cluster), url.PathEscape(ns))
+	resp, err := c.do(ctx, c.http, path, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return state.Configurations, nil
+	case http.StatusOK:
+		var body configResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return nil, fmt.Errorf("apollo: decode namespace %s: %w", ns, err)
+		}
+		c.mu.Lock()
+		state.ReleaseKey = body.ReleaseKey
+		state.Configurations = body.Configurations
+		// 在锁内复制状态，写缓存时不读取被其他请求修改的共享状态
+		cached := *state
+		c.mu.Unlock()
+		if err := writeCache(c.cacheFile(ns), &cached); err != nil {
+			config.GetLogger().Warnf("apollo: write cache for namespace %s: %v", ns, err)
+		}
+		return body.Configurations, nil
+	default:
+		return nil, fmt.Errorf("apollo: fetch namespace %s: unexpected status %d", ns, resp.StatusCode)
+	}
+}
+
+// fallback 服务端不可用时使用内存中的配置（热加载）或本地缓存（启动）
+func (c *Client) fallback(ns string, cause error) (map[string]string, error) {
+	c.mu.Lock()
+	state := c.states[ns]
+	if state.Configurations != nil {
+		c.mu.Unlock()
+		config.GetLogger().Warnf("apollo: %v, keep current config of namespace %s", cause, ns)
+		return state.Configurations, nil
+	}
+	c.mu.Unlock()
+
+	cached, err := readCache(c.cacheFile(ns))
+	if err != nil {
+		return nil, fmt.Errorf("%v, and no local cache available: %v", cause, err)
+	}
+	c.mu.Lock()
+	state.ReleaseKey = cached.ReleaseKey
+	state.Configurations = cached.Configurations
+	c.mu.Unlock()
+	config.GetLogger().Warnf("apollo: %v, start with local cache of namespace %s", cause, ns)
+	return cached.Configurations, nil
+}
+
+// waitNotifications 长轮询变更通知，无变更时服务端挂起后返回304。
+// 首次轮询返回当前的通知ID，此时按releaseKey检查Load之后是否有新的发布
+func (c *Client) waitNotifications(ctx context.Context) (bool, error) {
+	c.mu.Lock()
+	notifications := make([]notification, 0, len(c.states))
+	for _, ns := range c.opts.Namespaces {
+		notifications = append(notifications, notification{NamespaceName: ns, NotificationID: c.states[ns].notificationID})
+	}
+	c.mu.Unlock()
+
+	data, _ := json.Marshal(notifications)
+	query := url.Values{}
+	query.Set("appId", c.opts.AppID)
+	query.Set("cluster", c.opts.Cluster)
+	query.Set("notifications", string(data))
+	resp, err := c.do(ctx, c.poll, "/notifications/v2", query)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusOK:
+		var changes []notification
+		if err := json.NewDecoder(resp.Body).Decode(&changes); err != nil {
+			return false, fmt.Errorf("apollo: decode notifications: %w", err)
+		}
+		changed := false
+		for _, n := range changes {
+			if c.notificationID(n.NamespaceName) != initialNotificationID {
+				changed = true
+				continue
+			}
+			// 首次轮询：Load与本次轮询之间发布的配置不会再有通知，releaseKey变化时视为变更
+			released, err := c.released(ctx, n.NamespaceName)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || released
+		}
+		// 检查完成后才记录通知ID，失败重试时仍按首次轮询检查
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, n := range changes {
+			if state, ok := c.states[n.NamespaceName]; ok {
+				state.notificationID = n.NotificationID
+			}
+		}
+		return changed, nil
+	default:
+		return false, fmt.Errorf("apollo: poll notifications: unexpected status %d", resp.StatusCode)
+	}
+}
+
+func (c *Client) notificationID(ns string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if state, ok := c.states[ns]; ok {
+		return state.notificationID
+	}
+	return initialNotificationID
+}
+
+// released 重新拉取命名空间的配置，返回releaseKey是否变化
+func (c *Client) released(ctx context.Context, ns string) (bool, error) {
+	c.mu.Lock()
+	state, ok := c.states[ns]
+	if !ok {
+		c.mu.Unlock()
+		return false, nil
+	}
+	before := state.ReleaseKey
+	c.mu.Unlock()
+	if _, err := c.fetch(ctx, ns); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return state.ReleaseKey != before, nil
+}
+
+// do 发送请求，配置了访问密钥时添加签名
+func (c *Client) do(ctx context.Context, client *http.Client, path string, query url.Values) (*http.Response, error) {
+	pathWithQuery := path
+	if len(query) > 0 {
+		pathWithQuery += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.opts.Server+pathWithQuery, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha1.New, []byte(c.opts.Secret))
+		mac.Write([]byte(timestamp + "\n" + pathWithQuery))
+		req.Header.Set("Authorization", "Apollo "+c.opts.AppID+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		req.Header.Set("Timestamp", timestamp)
+	}
+	return client.Do(req)
+}
+
+func (c *Client) cacheFile(ns string) string {
+	name := strings.Join([]string{c.opts.AppID, c.opts.Cluster, ns}, "+") + ".json"
+	return filepath.Join(c.opts.CacheDir, name)
+}
+
+// decode 按命名空间格式转换为嵌套的配置字典
+func decode(ns string, configurations map[string]string) (map[string]interface{}, error) {
+	v := viper.New()
+	switch ext := strings.ToLower(filepath.Ext(ns)); ext {
+	case ".yml", ".yaml", ".json":
+		v.SetConfigType(strings.TrimPrefix(ext, "."))
+		if err := v.ReadConfig(strings.NewReader(configurations["content"])); err != nil {
+			return nil, fmt.Errorf("apollo: decode namespace %s: %w", ns, err)
+		}
+	default:
+		for key, value := range configurations {
+			v.Set(key, value)
+		}
+	}
+	return v.AllSettings(), nil
+}
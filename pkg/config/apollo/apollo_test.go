@@ -0,0 +1,350 @@
+package apollo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder 记录经过的请求与响应状态码后转发到FakeServer
+type recorder struct {
+	handler http.Handler
+
+	mu       sync.Mutex
+	requests []*http.Request
+	statuses []int
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	r.handler.ServeHTTP(sw, req)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.statuses = append(r.statuses, sw.status)
+}
+
+// configStatuses 拉取配置（/configs）请求的响应状态码
+func (r *recorder) configStatuses() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var statuses []int
+	for i, req := range r.requests {
+		if strings.HasPrefix(req.URL.Path, "/configs/") {
+			statuses = append(statuses, r.statuses[i])
+		}
+	}
+	return statuses
+}
+
+// newTestServer 启动FakeServer，并通过recorder对外提供服务
+func newTestServer(t *testing.T) (*FakeServer, *recorder, string) {
+	t.Helper()
+	fake, err := NewFakeServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Hold = 100 * time.Millisecond
+	rec := &recorder{handler: fake.server.Handler}
+	front := httptest.NewServer(rec)
+	t.Cleanup(func() {
+		front.Close()
+		fake.Close()
+	})
+	return fake, rec, front.URL
+}
+
+func newTestClient(t *testing.T, server string, mutate ...func(*Options)) *Client {
+	t.Helper()
+	opts := Options{
+		Server:      server,
+		AppID:       "app",
+		Cluster:     "default",
+		Namespaces:  []string{"application"},
+		CacheDir:    t.TempDir(),
+		Timeout:     time.Second,
+		PollTimeout: 5 * time.Second,
+	}
+	for _, m := range mutate {
+		m(&opts)
+	}
+	client, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// lookup 按"."分隔的路径读取嵌套的配置
+func lookup(data map[string]interface{}, key string) interface{} {
+	var v interface{} = data
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+func TestLoadMergesNamespacesInOrder(t *testing.T) {
+	fake, _, server := newTestServer(t)
+	fake.Publish("application", map[string]string{"listeners.web.port": "8080", "app.name": "demo"})
+	fake.Publish("override.yml", map[string]string{"content": "listeners:\n  web:\n    port: 9090\n"})
+	client := newTestClient(t, server, func(o *Options) {
+		o.Namespaces = []string{"application", "override.yml"}
+	})
+
+	data, err := client.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  string
+		want interface{}
+	}{
+		{"app.name", "demo"},
+		{"listeners.web.port", 9090},
+	}
+	for _, tt := range tests {
+		if got := lookup(data, tt.key); got != tt.want {
+			t.Errorf("%s = %#v, want %#v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLoadUnchangedReleaseUsesNotModified(t *testing.T) {
+	fake, rec, server := newTestServer(t)
+	fake.Publish("application", map[string]string{"app.name": "demo"})
+	client := newTestClient(t, server)
+
+	for i := 0; i < 2; i++ {
+		data, err := client.Load(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := lookup(data, "app.name"); got != "demo" {
+			t.Fatalf("load %d: app.name = %#v, want demo", i, got)
+		}
+	}
+	statuses := rec.configStatuses()
+	if len(statuses) != 2 || statuses[0] != http.StatusOK || statuses[1] != http.StatusNotModified {
+		t.Fatalf("config statuses = %v, want [200 304]", statuses)
+	}
+}
+
+func TestLoadFallsBackToCache(t *testing.T) {
+	fake, _, server := newTestServer(t)
+	fake.Publish("application", map[string]string{"app.name": "cached"})
+	cacheDir := t.TempDir()
+	withCache := func(o *Options) {
+		o.CacheDir = cacheDir
+	}
+	if _, err := newTestClient(t, server, withCache).Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer unavailable.Close()
+	data, err := newTestClient(t, unavailable.URL, withCache).Load(context.Background())
+	if err != nil {
+		t.Fatalf("load with cache: %v", err)
+	}
+	if got := lookup(data, "app.name"); got != "cached" {
+		t.Fatalf("app.name = %#v, want cached", got)
+	}
+
+	if _, err := newTestClient(t, unavailable.URL).Load(context.Background()); err == nil {
+		t.Fatal("load without server and cache must fail")
+	}
+}
+
+// watch 在后台监听变更，返回变更通知通道
+func watch(t *testing.T, client *Client) <-chan struct{} {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan struct{}, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Watch(ctx, func() {
+			changes <- struct{}{}
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return changes
+}
+
+func waitChange(t *testing.T, changes <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-changes:
+	case <-time.After(3 * time.Second):
+		t.Fatal("change was not notified")
+	}
+}
+
+func TestWatchNotifiesPublishedChanges(t *testing.T) {
+	fake, _, server := newTestServer(t)
+	fake.Publish("application", map[string]string{"app.name": "v1"})
+	client := newTestClient(t, server)
+	if _, err := client.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	changes := watch(t, client)
+
+	// 等待首次轮询记录通知ID，且未发布时不通知变更
+	select {
+	case <-changes:
+		t.Fatal("unexpected change before publish")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	fake.Publish("application", map[string]string{"app.name": "v2"})
+	waitChange(t, changes)
+	data, err := client.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup(data, "app.name"); got != "v2" {
+		t.Fatalf("app.name = %#v, want v2", got)
+	}
+}
+
+func TestWatchAppliesChangePublishedBeforeFirstPoll(t *testing.T) {
+	fake, _, server := newTestServer(t)
+	fake.Publish("application", map[string]string{"app.name": "v1"})
+	client := newTestClient(t, server)
+	if _, err := client.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	fake.Publish("application", map[string]string{"app.name": "v2"})
+
+	waitChange(t, watch(t, client))
+	data, err := client.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup(data, "app.name"); got != "v2" {
+		t.Fatalf("app.name = %#v, want v2", got)
+	}
+}
+
+func TestRequestsAreSigned(t *testing.T) {
+	fake, rec, server := newTestServer(t)
+	fake.Publish("application", map[string]string{"app.name": "demo"})
+	client := newTestClient(t, server, func(o *Options) {
+		o.Secret = "s3cr3t"
+	})
+	if _, err := client.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.requests) == 0 {
+		t.Fatal("no request recorded")
+	}
+	req := rec.requests[0]
+	timestamp := req.Header.Get("Timestamp")
+	if timestamp == "" {
+		t.Fatal("Timestamp header is missing")
+	}
+	mac := hmac.New(sha1.New, []byte("s3cr3t"))
+	mac.Write([]byte(timestamp + "\n" + req.URL.RequestURI()))
+	want := "Apollo app:" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("Authorization = %q, want %q", got, want)
+	}
+}
+
+func TestTimeoutCoversAllNamespaces(t *testing.T) {
+	client := newTestClient(t, "http://127.0.0.1:0", func(o *Options) {
+		o.Namespaces = []string{"application", "db", "redis.yml"}
+		o.Timeout = 20 * time.Second
+	})
+	if got := client.Timeout(); got != 60*time.Second {
+		t.Fatalf("Timeout() = %s, want 60s", got)
+	}
+}
+
+func TestLoadConcurrentWithWatch(t *testing.T) {
+	fake, _, server := newTestServer(t)
+	fake.Publish("application", map[string]string{"app.name": "v0"})
+	cacheDir := t.TempDir()
+	client := newTestClient(t, server, func(o *Options) {
+		o.CacheDir = cacheDir
+	})
+	if _, err := client.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	changes := watch(t, client)
+	// 等待首次轮询记录通知ID，之后的发布都会通知
+	time.Sleep(300 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := client.Load(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 5; i++ {
+		fake.Publish("application", map[string]string{"app.name": fmt.Sprintf("v%d", i)})
+		waitChange(t, changes)
+	}
+	wg.Wait()
+
+	data, err := client.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup(data, "app.name"); got != "v5" {
+		t.Fatalf("app.name = %#v, want v5", got)
+	}
+	cached, err := newTestClient(t, "http://127.0.0.1:0", func(o *Options) {
+		o.CacheDir = cacheDir
+	}).Load(context.Background())
+	if err != nil {
+		t.Fatalf("load from cache: %v", err)
+	}
+	if got := lookup(cached, "app.name"); got != "v5" {
+		t.Fatalf("cached app.name = %#v, want v5", got)
+	}
+}
@@ -0,0 +1,44 @@
+package apollo
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// writeCache 写入本地缓存，先写临时文件再重命名，避免进程退出时留下不完整的缓存
+func writeCache(file string, state *namespaceState) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// 同一命名空间可能被并发拉取，每次写入使用独立的临时文件
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+func readCache(file string) (*namespaceState, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	state := &namespaceState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,162 @@
+package apollo
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultHold 长轮询无变更时服务端的挂起时间，与Apollo服务端一致
+const defaultHold = 60 * time.Second
+
+type (
+	// FakeServer 进程内的Apollo服务端，实现配置拉取（/configs）与变更通知长轮询（/notifications/v2）接口，
+	// 用于离线开发与验证，如：
+	//
+	//	fake, _ := apollo.NewFakeServer("127.0.0.1:0")
+	//	fake.Publish("application", map[string]string{"listeners.web.port": "8080"})
+	//	// apollo_config.server 配置为 fake.URL()
+	FakeServer struct {
+		listener net.Listener
+		server   *http.Server
+		// Hold 长轮询无变更时的挂起时间
+		Hold time.Duration
+
+		mu         sync.Mutex
+		namespaces map[string]*fakeNamespace
+		nextID     int64
+		changed    chan struct{} // 发布时关闭并替换，唤醒挂起的长轮询
+	}
+
+	fakeNamespace struct {
+		configurations map[string]string
+		releaseKey     string
+		notificationID int64
+	}
+)
+
+// NewFakeServer 在addr上启动服务端，addr为"127.0.0.1:0"时使用随机端口
+func NewFakeServer(addr string) (*FakeServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &FakeServer{
+		listener:   listener,
+		Hold:       defaultHold,
+		namespaces: make(map[string]*fakeNamespace),
+		changed:    make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/configs/", s.handleConfigs)
+	mux.HandleFunc("/notifications/v2", s.handleNotifications)
+	s.server = &http.Server{Handler: mux}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// URL 服务端地址
+func (s *FakeServer) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+// Publish 发布命名空间的全部配置，yml、yaml、json格式的命名空间使用content作为Key
+func (s *FakeServer) Publish(namespace string, configurations map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	copied := make(map[string]string, len(configurations))
+	for k, v := range configurations {
+		copied[k] = v
+	}
+	s.namespaces[namespace] = &fakeNamespace{
+		configurations: copied,
+		releaseKey:     strconv.FormatInt(s.nextID, 10),
+		notificationID: s.nextID,
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Close 关闭服务端
+func (s *FakeServer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// handleConfigs GET /configs/{appId}/{cluster}/{namespace}?releaseKey=
+func (s *FakeServer) handleConfigs(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/configs/"), "/")
+	if len(parts) != 3 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	s.mu.Lock()
+	ns, ok := s.namespaces[parts[2]]
+	s.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.URL.Query().Get("releaseKey") == ns.releaseKey {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, configResponse{
+		AppID:          parts[0],
+		Cluster:        parts[1],
+		NamespaceName:  parts[2],
+		Configurations: ns.configurations,
+		ReleaseKey:     ns.releaseKey,
+	})
+}
+
+// handleNotifications GET /notifications/v2?notifications=[...]，存在更新的通知ID时立即返回，否则挂起Hold后返回304
+func (s *FakeServer) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	var watching []notification
+	if err := json.Unmarshal([]byte(r.URL.Query().Get("notifications")), &watching); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	timer := time.NewTimer(s.Hold)
+	defer timer.Stop()
+	for {
+		changes, wait := s.changes(watching)
+		if len(changes) > 0 {
+			writeJSON(w, changes)
+			return
+		}
+		select {
+		case <-wait:
+		case <-timer.C:
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// changes 返回通知ID有更新的命名空间，以及下一次发布的等待通道
+func (s *FakeServer) changes(watching []notification) ([]notification, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := make([]notification, 0)
+	for _, n := range watching {
+		if ns, ok := s.namespaces[n.NamespaceName]; ok && ns.notificationID != n.NotificationID {
+			changes = append(changes, notification{NamespaceName: n.NamespaceName, NotificationID: ns.notificationID})
+		}
+	}
+	return changes, s.changed
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/helper"
+	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/cast"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// bindTagName 绑定时使用的Key标签，与GetStruct保持一致
+const bindTagName = "json"
+
+// decodeErrorKey 提取mapstructure错误信息中的字段路径，如："'port' cannot parse 'abc' as int"
+var decodeErrorKey = regexp.MustCompile(`'([^']+)'`)
+
+type (
+	// Violation 配置绑定失败项，Key为完整的配置路径
+	Violation struct {
+		Key     string `json:"key"`
+		Message string `json:"message"`
+	}
+
+	// BindError 配置绑定失败，包含所有类型转换与校验失败项
+	BindError struct {
+		Namespace  string
+		Violations []Violation
+	}
+)
+
+func (e *BindError) Error() string {
+	var b strings.Builder
+	if e.Namespace == "" {
+		b.WriteString("config is invalid:")
+	} else {
+		fmt.Fprintf(&b, "config %s is invalid:", e.Namespace)
+	}
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "\n  - %s: %s", v.Key, v.Message)
+	}
+	return b.String()
+}
+
+// Bind 将命名空间下的配置绑定到结构体指针，Key使用json标签：
+//   - 配置缺失时使用 default:"..." 标签的默认值
+//   - 解析嵌套字段（包括列表元素）中的 ${key:def}、#{env:def} 动态值
+//   - 使用validate标签校验，所有失败项以配置路径列出，返回 *BindError
+func Bind(namespace string, out interface{}) error {
+	return GetWrapper(namespace).Bind(out)
+}
+
+// MustBind 同Bind，绑定失败时panic，用于启动阶段
+func MustBind(namespace string, out interface{}) {
+	if err := Bind(namespace, out); err != nil {
+		panic(err)
+	}
+}
+
+// Bind 将当前配置实例（命名空间）下的配置绑定到结构体指针，见 config.Bind
+func (c *Configuration) Bind(out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config bind: out must be a non-nil pointer to struct, got %T", out)
+	}
+	t := rv.Elem().Type()
+
+	data := c.settings()
+	c.applyDefaults(t, data, c.namespace)
+
+	violations := make([]Violation, 0)
+	// decodeFailed 类型转换失败的Key，字段保持零值，不再重复报告校验失败
+	decodeFailed := make(map[string]bool)
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          bindTagName,
+		WeaklyTypedInput: true,
+		Result:           out,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+			mapstructure.TextUnmarshallerHookFunc(),
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("config bind: %w", err)
+	}
+	if err := decoder.Decode(data); err != nil {
+		de, ok := err.(*mapstructure.Error)
+		if !ok {
+			return fmt.Errorf("config bind %s: %w", c.namespace, err)
+		}
+		var patterns []*regexp.Regexp
+		for _, msg := range de.Errors {
+			key := c.namespace
+			if m := decodeErrorKey.FindStringSubmatch(msg); m != nil {
+				key = joinKey(c.namespace, strings.ToLower(m[1]))
+			}
+			if patterns == nil {
+				patterns = sensitivePatterns(c)
+			}
+			if c.sensitive(patterns, key) {
+				msg = redactMessage(msg)
+			}
+			violations = append(violations, Violation{Key: key, Message: msg})
+			decodeFailed[key] = true
+		}
+	}
+
+	if err := helper.GetValidator().Struct(out); err != nil {
+		ves, ok := err.(validator.ValidationErrors)
+		if !ok {
+			return fmt.Errorf("config bind %s: %w", c.namespace, err)
+		}
+		translator, _ := helper.FindTranslator("en")
+		for _, fe := range ves {
+			key := joinKey(c.namespace, structNamespaceKey(t, fe.StructNamespace()))
+			if decodeFailed[key] {
+				continue
+			}
+			violations = append(violations, Violation{Key: key, Message: fe.Translate(translator)})
+		}
+	}
+
+	if len(violations) > 0 {
+		sort.SliceStable(violations, func(i, j int) bool {
+			return violations[i].Key < violations[j].Key
+		})
+		return &BindError{Namespace: c.namespace, Violations: violations}
+	}
+	return nil
+}
+
+// settings 构建当前命名空间下的配置树副本，叶子节点按读取优先级取值并解析动态值
+func (c *Configuration) settings() map[string]interface{} {
+	data := make(map[string]interface{})
+	v := c.viper()
+	for _, key := range v.AllKeys() {
+		rel := key
+		if c.namespace != "" {
+			if !strings.HasPrefix(key, c.namespace+".") {
+				continue
+			}
+			rel = strings.TrimPrefix(key, c.namespace+".")
+		}
+		setPath(data, strings.Split(rel, "."), c.resolveNested(key, v.Get(key)))
+	}
+	return data
+}
+
+// resolveNested 递归解析列表、字典中的动态值，返回副本，不修改原配置
+func (c *Configuration) resolveNested(key string, val interface{}) interface{} {
+	switch x := val.(type) {
+	case string:
+		return c.resolve(key, x, nil)
+	case []interface{}:
+		items := make([]interface{}, len(x))
+		for i, item := range x {
+			items[i] = c.resolveNested(fmt.Sprintf("%s[%d]", key, i), item)
+		}
+		return items
+	case map[string]interface{}, map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for k, item := range cast.ToStringMap(x) {
+			m[strings.ToLower(k)] = c.resolveNested(joinKey(key, k), item)
+		}
+		return m
+	default:
+		return val
+	}
+}
+
+// applyDefaults 按default标签填充缺失的配置，嵌套结构体、结构体列表与字典逐层填充
+func (c *Configuration) applyDefaults(t reflect.Type, data map[string]interface{}, path string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, squash, ok := fieldKey(f)
+		if !ok {
+			continue
+		}
+		ft := indirectType(f.Type)
+		if squash && ft.Kind() == reflect.Struct {
+			c.applyDefaults(ft, data, path)
+			continue
+		}
+		key := strings.ToLower(name)
+		fullKey := joinKey(path, key)
+		if def, ok := f.Tag.Lookup("default"); ok {
+			if _, exists := data[key]; !exists {
+				data[key] = c.resolve(fullKey, def, nil)
+			}
+		}
+
+		switch ft.Kind() {
+		case reflect.Struct:
+			if data[key] == nil {
+				sub := make(map[string]interface{})
+				c.applyDefaults(ft, sub, fullKey)
+				// 指针类型的结构体缺失时保持nil
+				if len(sub) > 0 && f.Type.Kind() != reflect.Ptr {
+					data[key] = sub
+				}
+			} else if sub, ok := data[key].(map[string]interface{}); ok {
+				c.applyDefaults(ft, sub, fullKey)
+			}
+		case reflect.Slice, reflect.Array:
+			et := indirectType(ft.Elem())
+			items, ok := data[key].([]interface{})
+			if !ok || et.Kind() != reflect.Struct {
+				continue
+			}
+			for idx, item := range items {
+				if sub, ok := item.(map[string]interface{}); ok {
+					c.applyDefaults(et, sub, fmt.Sprintf("%s[%d]", fullKey, idx))
+				}
+			}
+		case reflect.Map:
+			et := indirectType(ft.Elem())
+			items, ok := data[key].(map[string]interface{})
+			if !ok || et.Kind() != reflect.Struct {
+				continue
+			}
+			for k, item := range items {
+				if sub, ok := item.(map[string]interface{}); ok {
+					c.applyDefaults(et, sub, joinKey(fullKey, k))
+				}
+			}
+		}
+	}
+}
+
+// structNamespaceKey 将校验错误的结构体字段路径转换为配置路径，如：Options.Server.Port -> server.port
+func structNamespaceKey(t reflect.Type, ns string) string {
+	// 匿名结构体的字段路径不带类型名
+	if t.Name() != "" {
+		ns = strings.TrimPrefix(ns, t.Name()+".")
+	}
+	segs := strings.Split(ns, ".")
+	parts := make([]string, 0, len(segs))
+	cur := t
+	for _, seg := range segs {
+		name, idx := seg, ""
+		if i := strings.IndexByte(seg, '['); i >= 0 {
+			name, idx = seg[:i], seg[i:]
+		}
+		cur = indirectType(cur)
+		if cur.Kind() != reflect.Struct {
+			parts = append(parts, strings.ToLower(seg))
+			continue
+		}
+		f, ok := cur.FieldByName(name)
+		if !ok {
+			parts = append(parts, strings.ToLower(seg))
+			continue
+		}
+		cur = f.Type
+		if idx != "" {
+			cur = indirectType(cur).Elem()
+		}
+		key, squash, _ := fieldKey(f)
+		if squash {
+			continue
+		}
+		parts = append(parts, strings.ToLower(key)+idx)
+	}
+	return strings.Join(parts, ".")
+}
+
+// fieldKey 字段对应的配置Key，json标签为"-"时忽略
+func fieldKey(f reflect.StructField) (name string, squash bool, ok bool) {
+	tag := f.Tag.Get(bindTagName)
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, opt := range parts[1:] {
+		if opt == "squash" {
+			squash = true
+		}
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, squash, true
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func setPath(data map[string]interface{}, keys []string, value interface{}) {
+	for _, key := range keys[:len(keys)-1] {
+		sub, ok := data[key].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			data[key] = sub
+		}
+		data = sub
+	}
+	data[keys[len(keys)-1]] = value
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
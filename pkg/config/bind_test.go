@@ -0,0 +1,37 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBindReportsDecodeErrorOnce(t *testing.T) {
+	mustInitTestConfig(t, `
+svc:
+  port: "not-a-port"
+  timeout: 3s
+`)
+	var out struct {
+		Port    int    `json:"port" validate:"required,min=1"`
+		Name    string `json:"name" validate:"required"`
+		Timeout string `json:"timeout"`
+	}
+	err := Bind("svc", &out)
+	var be *BindError
+	if !errors.As(err, &be) {
+		t.Fatalf("Bind error = %v, want *BindError", err)
+	}
+	want := []string{"svc.name", "svc.port"}
+	if len(be.Violations) != len(want) {
+		t.Fatalf("violations = %+v, want one per key %v", be.Violations, want)
+	}
+	for i, key := range want {
+		if be.Violations[i].Key != key {
+			t.Fatalf("violation %d = %+v, want key %s", i, be.Violations[i], key)
+		}
+	}
+	if msg := be.Violations[1].Message; !strings.Contains(msg, "not-a-port") {
+		t.Fatalf("svc.port violation = %q, want the decode error", msg)
+	}
+}
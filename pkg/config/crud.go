@@ -4,17 +4,18 @@ import (
 	"github.com/chnyangzhen/kago-fly/pkg/constant"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cast"
-	"os"
 	"strings"
 	"time"
 )
 
+// GetString 读取全局配置Key的字符串值，与Configuration.GetString一致，解析占位符并解密加密值
 func GetString(key string) string {
-	return root.GetString(key)
+	return cast.ToString(GetWrapper("").doGet(key, nil))
 }
 
+// GetStringWithDefault 读取全局配置Key的字符串值，值为空时返回defaultValue
 func GetStringWithDefault(key string, defaultValue string) string {
-	v := root.GetString(key)
+	v := GetString(key)
 	if v == "" {
 		return defaultValue
 	}
@@ -42,14 +43,14 @@ func (c *Configuration) makeKey(key string) string {
 // ToStringMap 将当前配置实例（命名空间）下所有配置，转换成 map[string]any 类型的字典。
 func (c *Configuration) ToStringMap() map[string]interface{} {
 	if "" == c.namespace {
-		return c.root.AllSettings()
+		return c.viper().AllSettings()
 	}
-	return cast.ToStringMap(c.root.Get(c.namespace))
+	return cast.ToStringMap(c.viper().Get(c.namespace))
 }
 
 // Keys 获取当前配置实例（命名空间）下所有配置的键列表
 func (c *Configuration) Keys() []string {
-	v := c.root.Sub(c.namespace)
+	v := c.viper().Sub(c.namespace)
 	if v != nil {
 		return v.AllKeys()
 	}
@@ -64,9 +65,12 @@ func (c *Configuration) GetOrDefault(key string, def interface{}) interface{} {
 	return c.doGet(c.makeKey(key), def)
 }
 
-// Set 向当前配置实例以覆盖的方式设置Key-Value键值。
+// Set 向当前配置实例以覆盖的方式设置Key-Value键值。热加载后设置的值仍然生效。
 func (c *Configuration) Set(key string, value interface{}) {
-	c.root.Set(c.makeKey(key), value)
+	if l := currentLoader(); c.snapshot == nil && l != nil {
+		l.set(c.makeKey(key), value)
+	}
+	c.viper().Set(c.makeKey(key), value)
 }
 
 // SetKeyAlias 设置当前配置实例的Key与GlobalAlias的映射
@@ -83,13 +87,16 @@ func (c *Configuration) SetKeyAlias(keyAlias map[string]string) {
 
 // SetDefault 为当前配置实例设置单个默认值。与Viper的SetDefault一致，作用于当前配置实例。
 func (c *Configuration) SetDefault(key string, value interface{}) {
-	c.root.SetDefault(c.makeKey(key), value)
+	if l := currentLoader(); c.snapshot == nil && l != nil {
+		l.setDefault(c.makeKey(key), value)
+	}
+	c.viper().SetDefault(c.makeKey(key), value)
 }
 
 // SetDefaults 为当前配置实例设置一组默认值。与Viper的SetDefault一致，作用于当前配置实例。
 func (c *Configuration) SetDefaults(defaults map[string]interface{}) {
 	for key, val := range defaults {
-		c.root.SetDefault(c.makeKey(key), val)
+		c.SetDefault(key, val)
 	}
 }
 
@@ -100,7 +107,7 @@ func (c *Configuration) IsSet(keys ...string) bool {
 	}
 	// Any not set, return false
 	for _, key := range keys {
-		if !c.root.IsSet(c.makeKey(key)) {
+		if !c.viper().IsSet(c.makeKey(key)) {
 			return false
 		}
 	}
@@ -188,54 +195,23 @@ func (c *Configuration) GetStruct(key string, outptr interface{}) error {
 
 func (c *Configuration) GetStructTag(key, structTag string, outptr interface{}) error {
 	key = c.makeKey(key)
-	if !c.root.IsSet(key) {
+	if !c.viper().IsSet(key) {
 		return nil
 	}
-	return c.root.UnmarshalKey(key, outptr, func(opt *mapstructure.DecoderConfig) {
+	return c.viper().UnmarshalKey(key, outptr, func(opt *mapstructure.DecoderConfig) {
 		opt.TagName = structTag
 	})
 }
 
 func (c *Configuration) doGet(key string, indef interface{}) interface{} {
-	val := c.root.Get(key)
+	val := c.viper().Get(key)
 	if expr, ok := val.(string); ok {
-		pkey, pdef, ptype := ParseDynamicKey(expr)
-		var usedef interface{}
-		if indef != nil {
-			usedef = indef
-		} else {
-			usedef = pdef
-		}
-		switch ptype {
-		case constant.DynamicTypeLookupConfig:
-			// check circle key
-			if key == pkey {
-				return usedef
-			}
-			if c.root.IsSet(pkey) {
-				return c.doGet(pkey, usedef)
-			} else {
-				return usedef
-			}
-
-		case constant.DynamicTypeLookupEnv:
-			if ev, ok := os.LookupEnv(pkey); ok {
-				return ev
-			} else {
-				return usedef
-			}
-
-		case constant.DynamicTypeStaticValue:
-			return val
-
-		default:
-			return val
-		}
+		return c.resolve(key, expr, indef)
 	}
 	// check local alias
 	if nil == val {
 		if alias, ok := c.alias[key]; ok {
-			val = c.root.Get(alias)
+			val = c.viper().Get(alias)
 		}
 	}
 	if nil == val {
@@ -244,7 +220,32 @@ func (c *Configuration) doGet(key string, indef interface{}) interface{} {
 	return val
 }
 
-// ParseDynamicKey 解析动态值：配置参数：${key:defaultV}，环境变量：#{key:defaultV}
+// resolve 解析配置Key对应值中的占位符，见interpolator。
+// 整个值为单个占位符且引用不存在时，优先使用调用方传入的默认值；存在循环引用时返回调用方传入的默认值
+func (c *Configuration) resolve(key string, expr string, indef interface{}) interface{} {
+	v, secret := c.tree()
+	in := &interpolator{v: v, key: secret, stack: []string{key}}
+	if p, ok := wholePlaceholder(expr); ok {
+		val, found, err := in.evaluate(p)
+		if err != nil {
+			log.Warnf("resolve config %s: %v", key, err)
+			return indef
+		}
+		if !found && indef != nil {
+			return indef
+		}
+		return val
+	}
+	val, err := in.interpolate(expr)
+	if err != nil {
+		log.Warnf("resolve config %s: %v", key, err)
+		return indef
+	}
+	return val
+}
+
+// ParseDynamicKey 解析动态值：配置参数：${key:defaultV}，环境变量：#{key:defaultV}。
+// 仅识别整个值为单个占位符的情况，读取配置时按 Interpolate 完整解析
 func ParseDynamicKey(pattern string) (key string, def string, typ int) {
 	pattern = strings.TrimSpace(pattern)
 	size := len(pattern)
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/spf13/cast"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// SourceFile 配置文件
+	SourceFile = "file"
+	// SourceSet 代码中通过Set设置
+	SourceSet = "set"
+	// SourceDefault 代码中通过SetDefault设置的默认值
+	SourceDefault = "default"
+)
+
+// defaultSensitivePatterns 未配置 config.sensitive_patterns 时，Key匹配以下规则的配置输出时脱敏
+var defaultSensitivePatterns = []string{"password", "passwd", "secret", "token", "credential", "private_key", "access_key"}
+
+type (
+	// Entry 生效的配置项，Value为解析占位符、解密后的值，敏感配置已脱敏
+	Entry struct {
+		Key    string      `json:"key"`
+		Value  interface{} `json:"value"`
+		Source string      `json:"source"`
+		// Origin 来源详情：配置文件路径、环境变量名、命令行参数
+		Origin string `json:"origin,omitempty"`
+	}
+
+	origin struct {
+		source string
+		origin string
+	}
+)
+
+// Effective 返回前缀（命名空间）下所有生效的配置项及其来源，按Key排序，prefix为空时返回所有配置。
+// Key匹配 config.sensitive_patterns（正则，忽略大小写）、值为 ENC(...) 或引用了加密值的配置输出为 ******
+func Effective(prefix string) []Entry {
+	IsInitialized()
+	v, key := currentTree()
+	c := &Configuration{snapshot: v, key: key, alias: make(map[string]string)}
+	patterns := sensitivePatterns(c)
+
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	entries := make([]Entry, 0, len(keys))
+	for _, key := range keys {
+		if prefix != "" && key != prefix && !strings.HasPrefix(key, prefix+".") {
+			continue
+		}
+		entry := Entry{Key: key}
+		entry.Source, entry.Origin = sourceOf(key)
+
+		if c.sensitive(patterns, key) {
+			entry.Value = RedactedValue
+		} else {
+			entry.Value = c.resolveNested(key, v.Get(key))
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// sourceOf 配置项的来源，优先级与读取时一致：Set > 命令行、环境变量、配置文件 > SetDefault
+func sourceOf(key string) (string, string) {
+	l := currentLoader()
+	if l == nil {
+		return "", ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.overrides[key]; ok {
+		return SourceSet, ""
+	}
+	// AutomaticEnv：Key对应的大写环境变量
+	if env := strings.ToUpper(key); !strings.HasPrefix(env, constant.EnvPrefix) {
+		if _, ok := os.LookupEnv(env); ok {
+			return SourceEnv, env
+		}
+	}
+	if o, ok := l.origins[key]; ok {
+		return o.source, o.origin
+	}
+	if _, ok := l.defaults[key]; ok {
+		return SourceDefault, ""
+	}
+	return "", ""
+}
+
+// SensitiveKeys 返回按名称判断是否敏感的函数，名称匹配 config.sensitive_patterns 时为敏感，配置未加载时使用默认规则。
+// 用于日志等按字段名脱敏的场景
+func SensitiveKeys() func(name string) bool {
+	patterns := compilePatterns(defaultSensitivePatterns)
+	if Loaded() {
+		v, key := currentTree()
+		patterns = sensitivePatterns(&Configuration{snapshot: v, key: key, alias: make(map[string]string)})
+	}
+	return func(name string) bool {
+		return matchAny(patterns, name)
+	}
+}
+
+func sensitivePatterns(c *Configuration) []*regexp.Regexp {
+	key := MakeKey(constant.ConfigConfig, "sensitive_patterns")
+	exprs := defaultSensitivePatterns
+	if c.viper().IsSet(key) {
+		exprs = cast.ToStringSlice(c.doGet(key, nil))
+	}
+	return compilePatterns(exprs)
+}
+
+func compilePatterns(exprs []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile("(?i)" + expr)
+		if err != nil {
+			re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(expr))
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+func matchAny(patterns []*regexp.Regexp, key string) bool {
+	for _, re := range patterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
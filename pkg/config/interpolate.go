@@ -0,0 +1,247 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+	"os"
+	"sort"
+	"strings"
+)
+
+type (
+	// placeholder 占位符：配置参数 ${key:def}，环境变量 #{key:def}
+	placeholder struct {
+		env    bool
+		key    string
+		def    string
+		hasDef bool
+	}
+
+	// interpolator 解析配置字符串中的占位符，支持：
+	//   - 一个值中包含多个占位符，如："http://${host}:${port}/api"
+	//   - 配置参数与环境变量混合使用，默认值中嵌套占位符，如："${db.host:#{DB_HOST:localhost}}"
+	//   - 转义："\${"、"\#{" 输出原样的 "${"、"#{"
+	//   - 跨Key的循环引用检测，如：a -> b -> a；环经过带默认值的占位符时使用默认值，如：port: "${port:8080}"
+	//   - 整个值为 ENC(...) 时解密，解密后的明文不再解析占位符
+	interpolator struct {
+		v      *viper.Viper
+		key    []byte   // 配置树对应的解密密钥
+		stack  []string // 正在解析的配置Key
+		secret bool     // 解析过程中是否读取了加密值，用于按来源脱敏
+	}
+
+	// CycleError 配置占位符循环引用
+	CycleError struct {
+		Path []string
+	}
+)
+
+func (e *CycleError) Error() string {
+	return "config placeholder cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// Interpolate 解析字符串中的占位符，引用的配置取当前生效的配置
+func Interpolate(s string) (string, error) {
+	IsInitialized()
+	v, key := currentTree()
+	in := &interpolator{v: v, key: key}
+	val, err := in.interpolate(s)
+	if err != nil {
+		return "", err
+	}
+	return cast.ToString(val), nil
+}
+
+// lookup 获取配置Key的值并解析其中的占位符，found表示配置是否存在
+func (in *interpolator) lookup(key string) (val interface{}, found bool, err error) {
+	for i, k := range in.stack {
+		if k == key {
+			path := append(append([]string{}, in.stack[i:]...), key)
+			return nil, true, &CycleError{Path: path}
+		}
+	}
+	if !in.v.IsSet(key) {
+		return nil, false, nil
+	}
+	val = in.v.Get(key)
+	s, ok := val.(string)
+	if !ok {
+		return val, true, nil
+	}
+	in.stack = append(in.stack, key)
+	defer func() {
+		in.stack = in.stack[:len(in.stack)-1]
+	}()
+	val, err = in.interpolate(s)
+	return val, true, err
+}
+
+// interpolate 解析字符串中的所有占位符。整个字符串为单个占位符时保留引用值的类型
+func (in *interpolator) interpolate(s string) (interface{}, error) {
+	if IsEncrypted(s) {
+		in.secret = true
+		return decryptValue(s, in.key)
+	}
+	if p, ok := wholePlaceholder(s); ok {
+		val, _, err := in.evaluate(p)
+		return val, err
+	}
+	if !strings.Contains(s, "{") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] == '\\' && isOpening(s, i+1) {
+			b.WriteString(s[i+1 : i+3])
+			i += 3
+			continue
+		}
+		if isOpening(s, i) {
+			if p, end, ok := scanPlaceholder(s, i); ok {
+				val, _, err := in.evaluate(p)
+				if err != nil {
+					return nil, err
+				}
+				b.WriteString(cast.ToString(val))
+				i = end
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String(), nil
+}
+
+// revealsSecret 解析值（包括列表、字典中的值）的过程中是否读取了加密值
+func (in *interpolator) revealsSecret(val interface{}) bool {
+	switch x := val.(type) {
+	case string:
+		in.interpolate(x)
+	case []interface{}:
+		for _, item := range x {
+			in.revealsSecret(item)
+		}
+	case map[string]interface{}:
+		for _, item := range x {
+			in.revealsSecret(item)
+		}
+	}
+	return in.secret
+}
+
+// evaluate 计算占位符的值，found表示引用的配置或环境变量是否存在，不存在时返回默认值
+func (in *interpolator) evaluate(p placeholder) (val interface{}, found bool, err error) {
+	key, err := in.interpolate(p.key)
+	if err != nil {
+		return nil, false, err
+	}
+	name := strings.TrimSpace(cast.ToString(key))
+	if p.env {
+		if ev, ok := os.LookupEnv(name); ok {
+			return ev, true, nil
+		}
+	} else {
+		val, found, err := in.lookup(name)
+		var ce *CycleError
+		if errors.As(err, &ce) && p.hasDef && in.onStack(ce.Path[0]) {
+			// 环经过当前占位符，使用默认值
+			found, err = false, nil
+		}
+		if err != nil || found {
+			return val, found, err
+		}
+	}
+	if !p.hasDef {
+		return "", false, nil
+	}
+	val, err = in.interpolate(p.def)
+	return val, false, err
+}
+
+// onStack 配置Key是否正在解析中
+func (in *interpolator) onStack(key string) bool {
+	for _, k := range in.stack {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// wholePlaceholder 判断整个字符串（去除首尾空白）是否为单个占位符
+func wholePlaceholder(s string) (placeholder, bool) {
+	s = strings.TrimSpace(s)
+	if !isOpening(s, 0) {
+		return placeholder{}, false
+	}
+	p, end, ok := scanPlaceholder(s, 0)
+	return p, ok && end == len(s)
+}
+
+func isOpening(s string, i int) bool {
+	return i+1 < len(s) && (s[i] == '$' || s[i] == '#') && s[i+1] == '{'
+}
+
+// scanPlaceholder 从位置i开始扫描一个完整的占位符，返回占位符及结束位置；括号不匹配时ok为false
+func scanPlaceholder(s string, i int) (p placeholder, end int, ok bool) {
+	depth := 0
+	sep := -1
+	start := i + 2
+	for j := start; j < len(s); j++ {
+		switch {
+		case s[j] == '\\' && isOpening(s, j+1):
+			j += 2
+		case s[j] == '{':
+			depth++
+		case s[j] == ':' && depth == 0 && sep < 0:
+			sep = j
+		case s[j] == '}':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			p = placeholder{env: s[i] == '#', key: s[start:j]}
+			if sep >= 0 {
+				p.key, p.def, p.hasDef = s[start:sep], s[sep+1:j], true
+			}
+			return p, j + 1, true
+		}
+	}
+	return placeholder{}, 0, false
+}
+
+// checkValues 使用配置树对应的密钥，检查配置树中所有占位符的循环引用与加密值的解密
+func checkValues(v *viper.Viper, secret []byte) error {
+	problems := make(map[string]bool)
+	for _, key := range v.AllKeys() {
+		in := &interpolator{v: v, key: secret}
+		_, _, err := in.lookup(key)
+		if ce, ok := err.(*CycleError); ok {
+			// 同一个环从不同的Key开始解析，统一从最小的Key开始，只报告一次
+			keys := ce.Path[:len(ce.Path)-1]
+			min := 0
+			for i, k := range keys {
+				if k < keys[min] {
+					min = i
+				}
+			}
+			path := append(append(append([]string{}, keys[min:]...), keys[:min]...), keys[min])
+			problems[(&CycleError{Path: path}).Error()] = true
+		} else if err != nil {
+			problems[fmt.Sprintf("%s: %v", key, err)] = true
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(problems))
+	for msg := range problems {
+		msgs = append(msgs, msg)
+	}
+	sort.Strings(msgs)
+	return fmt.Errorf("%s", strings.Join(msgs, "; "))
+}
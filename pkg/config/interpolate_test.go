@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"github.com/spf13/viper"
+	"strings"
+	"testing"
+)
+
+func newTestViper(values map[string]interface{}) *viper.Viper {
+	v := viper.New()
+	for key, value := range values {
+		v.Set(key, value)
+	}
+	return v
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("KAGO_TEST_DB_HOST", "db.internal")
+	v := newTestViper(map[string]interface{}{
+		"host":    "localhost",
+		"port":    8080,
+		"which":   "host",
+		"db.name": "app",
+		"chain.a": "${chain.b}",
+		"chain.b": "${chain.c}/b",
+		"chain.c": "c",
+		"self":    "${self:8080}",
+		"cycle.a": "${cycle.b:1}",
+		"cycle.b": "${cycle.a}",
+	})
+	tests := []struct {
+		name string
+		in   string
+		want interface{}
+	}{
+		{"plain", "plain value", "plain value"},
+		{"single placeholder keeps type", "${port}", 8080},
+		{"single placeholder with spaces", "  ${port}  ", 8080},
+		{"multiple placeholders", "http://${host}:${port}/api", "http://localhost:8080/api"},
+		{"config and env mixed", "${db.name}@#{KAGO_TEST_DB_HOST}", "app@db.internal"},
+		{"default", "${missing:fallback}", "fallback"},
+		{"empty default", "${missing:}", ""},
+		{"missing without default", "[${missing}]", "[]"},
+		{"default with colon", "${missing:http://example.com}", "http://example.com"},
+		{"nested env default", "${missing:#{KAGO_TEST_MISSING:local}}", "local"},
+		{"nested env found", "${missing:#{KAGO_TEST_DB_HOST:local}}", "db.internal"},
+		{"nested config default", "${missing:${host}}", "localhost"},
+		{"deeply nested default", "${m1:${m2:#{KAGO_TEST_MISSING:${port}}}}", 8080},
+		{"nested key", "${${which}}", "localhost"},
+		{"chained references", "${chain.a}", "c/b"},
+		{"chained inside text", "[${chain.a}]", "[c/b]"},
+		{"self reference with default", "${self}", "8080"},
+		{"cycle through default", "${cycle.a}", "1"},
+		{"cycle back to default", "${cycle.b}", "1"},
+		{"escaped config", `\${host}`, "${host}"},
+		{"escaped env", `\#{HOME}`, "#{HOME}"},
+		{"escaped and resolved", `\${host}=${host}`, "${host}=localhost"},
+		{"escaped inside default", `${missing:\${host}}`, "${host}"},
+		{"unclosed", "${host", "${host"},
+		{"braces without marker", "{host}", "{host}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &interpolator{v: v}
+			got, err := in.interpolate(tt.in)
+			if err != nil {
+				t.Fatalf("interpolate(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Fatalf("interpolate(%q) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateCycle(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		in     string
+		want   string
+	}{
+		{"self", map[string]interface{}{"a": "${a}"}, "${a}", "a -> a"},
+		{"two keys", map[string]interface{}{"a": "${b}", "b": "${a}"}, "${a}", "a -> b -> a"},
+		{"inside text", map[string]interface{}{"a": "x${b}", "b": "y${c}", "c": "${a}"}, "${a}", "a -> b -> c -> a"},
+		{"through default", map[string]interface{}{"a": "${missing:${b}}", "b": "${a}"}, "${a}", "a -> b -> a"},
+		{"through nested key", map[string]interface{}{"a": "${${b}}", "b": "${a}"}, "${b}", "b -> a -> b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &interpolator{v: newTestViper(tt.values)}
+			_, err := in.interpolate(tt.in)
+			var ce *CycleError
+			if !errors.As(err, &ce) {
+				t.Fatalf("interpolate(%q) error = %v, want *CycleError", tt.in, err)
+			}
+			if got := strings.Join(ce.Path, " -> "); got != tt.want {
+				t.Fatalf("cycle = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitConfigFailsOnCycle(t *testing.T) {
+	err := initTestConfig(t, `
+app:
+  url: "http://${app.host}/"
+  host: "${app.alias}"
+  alias: "${app.host}"
+  name: "${app.name}"
+other: "${app.name:x}"
+`)
+	if err == nil {
+		t.Fatal("config with placeholder cycles must fail to load")
+	}
+	msg := err.Error()
+	for _, cycle := range []string{
+		"config placeholder cycle: app.alias -> app.host -> app.alias",
+		"config placeholder cycle: app.name -> app.name",
+	} {
+		if strings.Count(msg, cycle) != 1 {
+			t.Errorf("error must report %q exactly once: %s", cycle, msg)
+		}
+	}
+}
+
+func TestInitConfigResolvesPlaceholders(t *testing.T) {
+	t.Setenv("KAGO_TEST_DB_HOST", "db.internal")
+	mustInitTestConfig(t, `
+db:
+  host: "#{KAGO_TEST_DB_HOST:localhost}"
+  port: 5432
+  url: 'postgres://${db.host}:${db.port}/app?opt=\${raw}'
+server:
+  port: "${server.port:8080}"
+`)
+	if got := GetWrapper("db").GetString("url"); got != "postgres://db.internal:5432/app?opt=${raw}" {
+		t.Fatalf("db.url = %q", got)
+	}
+	if got := GetStringWithDefault("db.url", "none"); got != "postgres://db.internal:5432/app?opt=${raw}" {
+		t.Fatalf("GetStringWithDefault(db.url) = %q", got)
+	}
+	if got := GetWrapper("server").GetInt("port"); got != 8080 {
+		t.Fatalf("server.port = %d, self reference must use its default", got)
+	}
+	if got, err := Interpolate("${db.port:0}-${db.missing:none}"); err != nil || got != "5432-none" {
+		t.Fatalf("Interpolate = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"sort"
+	"strings"
+)
+
+const (
+	// SourceFlag 通过命令行参数 --set key=value 覆盖
+	SourceFlag = "flag"
+	// SourceEnv 通过带前缀的环境变量覆盖，如：KAGO_LISTENERS__WEB__PORT
+	SourceEnv = "env"
+)
+
+// Override 启动参数对配置的覆盖，优先级：flag > env > 配置文件
+type Override struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	// Origin 覆盖的来源，如："--set listeners.web.port"、"KAGO_LISTENERS__WEB__PORT"
+	Origin string `json:"origin"`
+	// Shadowed 被当前覆盖屏蔽的低优先级来源
+	Shadowed []string `json:"shadowed,omitempty"`
+}
+
+// WithSets 设置命令行覆盖的配置，格式为 key=value，key使用"."分隔层级
+func WithSets(pairs ...string) Option {
+	return func(l *loader) {
+		l.sets = append(l.sets, pairs...)
+	}
+}
+
+// Overrides 返回生效的配置覆盖及其来源，按Key排序
+func Overrides() []Override {
+	l := currentLoader()
+	if l == nil {
+		return []Override{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	dst := make([]Override, len(l.applied))
+	copy(dst, l.applied)
+	return dst
+}
+
+// nested 将覆盖转换为嵌套的配置字典，如：a.b=1 -> {a: {b: 1}}
+func (o Override) nested() map[string]interface{} {
+	keys := strings.Split(o.Key, ".")
+	var value interface{} = o.Value
+	for i := len(keys) - 1; i >= 0; i-- {
+		value = map[string]interface{}{keys[i]: value}
+	}
+	return value.(map[string]interface{})
+}
+
+// resolveOverrides 合并环境变量与命令行参数中的覆盖配置，同一个Key命令行参数优先
+func resolveOverrides(sets []string, environ []string) ([]Override, error) {
+	overrides := make(map[string]*Override)
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, constant.EnvPrefix) || len(name) == len(constant.EnvPrefix) {
+			continue
+		}
+		key := envKey(name)
+		overrides[key] = &Override{Key: key, Value: value, Source: SourceEnv, Origin: name}
+	}
+	for _, pair := range sets {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+			return nil, fmt.Errorf("invalid --%s %q, expected key=value", constant.Set, pair)
+		}
+		origin := fmt.Sprintf("--%s %s", constant.Set, key)
+		override := &Override{Key: key, Value: value, Source: SourceFlag, Origin: origin}
+		if prev, ok := overrides[key]; ok {
+			override.Shadowed = append(prev.Shadowed, prev.Origin)
+		}
+		overrides[key] = override
+	}
+
+	result := make([]Override, 0, len(overrides))
+	for _, override := range overrides {
+		result = append(result, *override)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+// envKey 环境变量名转换为配置Key：去除前缀，"__"转换为"."，转小写。
+// 如：KAGO_LISTENERS__WEB__PRE_STOP_DELAY -> listeners.web.pre_stop_delay
+func envKey(name string) string {
+	key := strings.TrimPrefix(name, constant.EnvPrefix)
+	return strings.ToLower(strings.ReplaceAll(key, constant.EnvKeySeparator, "."))
+}
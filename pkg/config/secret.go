@@ -0,0 +1,176 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	secretPrefix = "ENC("
+	secretSuffix = ")"
+	// RedactedValue 脱敏后的值
+	RedactedValue = "******"
+)
+
+// ErrNoSecretKey 未配置解密密钥
+var ErrNoSecretKey = fmt.Errorf("config secret key is not configured, set %s or %s",
+	constant.EnvConfigSecretKey, constant.EnvConfigSecretKeyFile)
+
+// secretKey 当前配置树使用的密钥，加载配置时从环境变量或密钥文件读取，未配置时为nil。
+// 与root一起由rootMu保护，在commit中随配置树一起替换
+var secretKey []byte
+
+// LoadSecretKey 读取配置解密密钥：环境变量 CONFIG_SECRET_KEY 优先，其次为 CONFIG_SECRET_KEY_FILE 指定的密钥文件。
+// 密钥为base64编码的16、24或32字节（AES-128、AES-192、AES-256），未配置时返回ErrNoSecretKey
+func LoadSecretKey() ([]byte, error) {
+	encoded, ok := os.LookupEnv(constant.EnvConfigSecretKey)
+	source := constant.EnvConfigSecretKey
+	if !ok || encoded == "" {
+		file, ok := os.LookupEnv(constant.EnvConfigSecretKeyFile)
+		if !ok || file == "" {
+			return nil, ErrNoSecretKey
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read config secret key file: %w", err)
+		}
+		encoded, source = string(data), file
+	}
+	key, err := ParseSecretKey(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("config secret key from %s: %w", source, err)
+	}
+	return key, nil
+}
+
+// ParseSecretKey 解析base64编码的密钥
+func ParseSecretKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 key: %w", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("invalid key size %d, expected 16, 24 or 32 bytes", len(key))
+	}
+}
+
+// GenerateSecretKey 生成base64编码的32字节（AES-256）密钥
+func GenerateSecretKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt 使用AES-GCM加密明文，返回可直接写入配置文件的 ENC(...) 值
+func Encrypt(plaintext string, key []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed) + secretSuffix, nil
+}
+
+// Decrypt 解密 ENC(...) 值
+func Decrypt(value string, key []byte) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("config secret: value is not in ENC(...) form")
+	}
+	value = strings.TrimSpace(value)
+	sealed, err := base64.StdEncoding.DecodeString(value[len(secretPrefix) : len(value)-len(secretSuffix)])
+	if err != nil {
+		return "", fmt.Errorf("config secret: invalid base64: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("config secret: ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("config secret: decrypt failed, wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted 判断值是否为 ENC(...) 加密值
+func IsEncrypted(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, secretPrefix) && strings.HasSuffix(value, secretSuffix)
+}
+
+// Redact 按配置Key脱敏：Key匹配 config.sensitive_patterns，或值为 ENC(...)、引用了加密值（如 "${db.password}"）时返回 ******，
+// 否则原样返回value，用于输出配置值
+func Redact(key string, value interface{}) interface{} {
+	IsInitialized()
+	v, secret := currentTree()
+	c := &Configuration{snapshot: v, key: secret, alias: make(map[string]string)}
+	if c.sensitive(sensitivePatterns(c), key) {
+		return RedactedValue
+	}
+	return value
+}
+
+// sensitive Key匹配敏感规则，或值（包括列表、字典中的值）为加密值、引用了加密值
+func (c *Configuration) sensitive(patterns []*regexp.Regexp, key string) bool {
+	if matchAny(patterns, key) {
+		return true
+	}
+	v, secret := c.tree()
+	in := &interpolator{v: v, key: secret}
+	return in.revealsSecret(v.Get(key))
+}
+
+// redactMessage 敏感配置的错误信息中去除值，如："cannot parse 'Port' as int: ******"
+func redactMessage(msg string) string {
+	if idx := strings.Index(msg, ":"); idx >= 0 {
+		return msg[:idx] + ": " + RedactedValue
+	}
+	return RedactedValue
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("config secret: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// loadSecretKey 每次加载配置时重新读取密钥，未配置密钥不是错误，读取到加密值时才报错
+func loadSecretKey() ([]byte, error) {
+	key, err := LoadSecretKey()
+	if err != nil && !errors.Is(err, ErrNoSecretKey) {
+		return nil, err
+	}
+	return key, nil
+}
+
+// decryptValue 使用配置树对应的密钥解密
+func decryptValue(value string, key []byte) (string, error) {
+	if key == nil {
+		return "", ErrNoSecretKey
+	}
+	return Decrypt(value, key)
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/viper"
+	"sync"
+	"time"
+)
+
+// SourceRemote 远程配置源
+const SourceRemote = "remote"
+
+// defaultSourceTimeout 未实现 SourceTimeout 的配置源的加载超时时间
+const defaultSourceTimeout = 10 * time.Second
+
+type (
+	// Source 远程配置源，如Apollo。按注册顺序在配置文件之后合并，优先级高于配置文件、低于环境变量与命令行覆盖
+	Source interface {
+		// Name 配置源名称，用于输出配置来源
+		Name() string
+		// Load 加载全部配置，返回嵌套的配置字典。远程不可用时可以返回本地缓存
+		Load(ctx context.Context) (map[string]interface{}, error)
+		// Watch 监听配置变更，变更时调用onChange触发重新加载，ctx取消时返回
+		Watch(ctx context.Context, onChange func()) error
+	}
+
+	// SourceTimeout 配置源实现时使用其返回的加载超时时间，如Apollo按配置的超时时间与命名空间数量计算
+	SourceTimeout interface {
+		Timeout() time.Duration
+	}
+
+	// SourceFactory 根据配置文件中命名空间下的配置创建配置源，未启用时返回nil
+	SourceFactory func(c *Configuration) (Source, error)
+
+	namedFactory struct {
+		namespace string
+		factory   SourceFactory
+	}
+)
+
+var (
+	factoriesMu sync.Mutex
+	factories   []namedFactory
+
+	// stopSources 停止监听远程配置源
+	stopSources context.CancelFunc
+)
+
+// RegisterSource 注册配置源工厂，首次加载配置时使用配置文件中namespace下的配置创建配置源，如：apollo包在init中注册
+func RegisterSource(namespace string, factory SourceFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories = append(factories, namedFactory{namespace: namespace, factory: factory})
+}
+
+// WithSources 添加配置源，在通过RegisterSource注册的配置源之后合并
+func WithSources(sources ...Source) Option {
+	return func(l *loader) {
+		l.extraSources = append(l.extraSources, sources...)
+	}
+}
+
+// initSources 首次加载时创建配置源，配置源的配置来自配置文件与命令行、环境变量覆盖
+func (l *loader) initSources(fileConfig *viper.Viper, overrides []Override, key []byte) error {
+	l.mu.Lock()
+	created := l.sources != nil
+	l.mu.Unlock()
+	if created {
+		return nil
+	}
+	boot := viper.New()
+	if err := boot.MergeConfigMap(fileConfig.AllSettings()); err != nil {
+		return err
+	}
+	for _, override := range overrides {
+		if err := boot.MergeConfigMap(override.nested()); err != nil {
+			return err
+		}
+	}
+	factoriesMu.Lock()
+	fs := make([]namedFactory, len(factories))
+	copy(fs, factories)
+	factoriesMu.Unlock()
+
+	sources := make([]Source, 0, len(fs)+len(l.extraSources))
+	for _, f := range fs {
+		c := &Configuration{namespace: f.namespace, snapshot: boot, key: key, alias: make(map[string]string)}
+		src, err := f.factory(c)
+		if err != nil {
+			return fmt.Errorf("create config source %s: %w", f.namespace, err)
+		}
+		if src != nil {
+			sources = append(sources, src)
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sources = append(sources, l.extraSources...)
+	return nil
+}
+
+// configSources 已创建的配置源
+func (l *loader) configSources() []Source {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Source{}, l.sources...)
+}
+
+// sourceTimeout 配置源的加载超时时间
+func sourceTimeout(src Source) time.Duration {
+	if t, ok := src.(SourceTimeout); ok && t.Timeout() > 0 {
+		return t.Timeout()
+	}
+	return defaultSourceTimeout
+}
+
+// mergeSources 按顺序加载并合并配置源，记录每个Key的来源
+func (l *loader) mergeSources(v *viper.Viper, origins map[string]origin) error {
+	for _, src := range l.configSources() {
+		ctx, cancel := context.WithTimeout(context.Background(), sourceTimeout(src))
+		data, err := src.Load(ctx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("load config source %s: %w", src.Name(), err)
+		}
+		if err := v.MergeConfigMap(data); err != nil {
+			return fmt.Errorf("merge config source %s: %w", src.Name(), err)
+		}
+		sv := viper.New()
+		if err := sv.MergeConfigMap(data); err == nil {
+			for _, key := range sv.AllKeys() {
+				origins[key] = origin{source: SourceRemote, origin: src.Name()}
+			}
+		}
+	}
+	return nil
+}
+
+// watchSources 监听所有配置源，配置变更时重新加载配置
+func watchSources(l *loader) {
+	sources := l.configSources()
+	if len(sources) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopSources = cancel
+	for _, src := range sources {
+		go func(src Source) {
+			log.Infof("watching config source: %s", src.Name())
+			if err := src.Watch(ctx, scheduleReload); err != nil && ctx.Err() == nil {
+				log.Errorf("config source %s watch stopped: %v", src.Name(), err)
+			}
+		}(src)
+	}
+}
@@ -8,11 +8,16 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 var (
-	// 显示调用Set设置值 > 命令行参数（flag）> 环境变量-配置文件 > key/value存储 > 默认值
+	// 显示调用Set设置值 > 命令行参数（--set）> 环境变量（KAGO_前缀）> 远程配置源（按注册顺序）> 配置文件 > 默认值
 	root *viper.Viper
+	// rootMu 保护root、ld、secretKey的替换，热加载时整体替换为新的配置树
+	rootMu sync.RWMutex
+	// ld 配置加载器，热加载时使用相同的加载参数重新构建配置树
+	ld *loader
 )
 
 // ViperLifecycle Viper组件生命周期
@@ -21,15 +26,15 @@ type ViperLifecycle int
 // Configuration Viper配置包装器
 type Configuration struct {
 	namespace string
-	root      *viper.Viper
+	snapshot  *viper.Viper      // 配置快照，为nil时始终读取最新的配置
+	key       []byte            // 配置快照对应的解密密钥
 	alias     map[string]string // 本地Key别名
 }
 
-// GetWrapper 获取Viper配置包装器
+// GetWrapper 获取Viper配置包装器，读取的始终是最新（热加载后）的配置
 func GetWrapper(namespace string) *Configuration {
 	return &Configuration{
 		namespace: namespace,
-		root:      root,
 		alias:     make(map[string]string),
 	}
 }
@@ -37,40 +42,372 @@ func GetWrapper(namespace string) *Configuration {
 // GlobalConfig 获取全局配置
 func GlobalConfig() *viper.Viper {
 	IsInitialized()
-	return root
+	return current()
 }
 
 // IsInitialized 是否已经初始化
 func IsInitialized() {
-	if root == nil {
+	if current() == nil {
 		panic("Viper component is not initialized, Please load the viper component ")
 	}
 }
 
-func InitConfig(configNames []string) error {
-	// 获取所有的配置文件
-	err := filepath.Walk(constant.DefaultConfigPath, func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() {
-			dir := filepath.Dir(path)
-			viper.AddConfigPath(dir)
-			// 读取配置文件
+// Loaded 配置是否已经加载，供可独立于配置组件使用的组件判断
+func Loaded() bool {
+	return current() != nil
+}
+
+func current() *viper.Viper {
+	rootMu.RLock()
+	defer rootMu.RUnlock()
+	return root
+}
+
+// currentTree 当前生效的配置树及其解密密钥
+func currentTree() (*viper.Viper, []byte) {
+	rootMu.RLock()
+	defer rootMu.RUnlock()
+	return root, secretKey
+}
+
+func (c *Configuration) viper() *viper.Viper {
+	if c.snapshot != nil {
+		return c.snapshot
+	}
+	return current()
+}
+
+// tree 配置实例读取的配置树及其解密密钥
+func (c *Configuration) tree() (*viper.Viper, []byte) {
+	if c.snapshot != nil {
+		return c.snapshot, c.key
+	}
+	return currentTree()
+}
+
+// Option 配置加载选项
+type Option func(l *loader)
+
+// WithProfiles 设置激活的Profile，按顺序在基础配置之后合并 {name}-{profile} 配置文件
+func WithProfiles(profiles ...string) Option {
+	return func(l *loader) {
+		for _, profile := range profiles {
+			if profile = strings.TrimSpace(profile); profile != "" && !helper.ContainsString(l.profiles, profile) {
+				l.profiles = append(l.profiles, profile)
+			}
+		}
+	}
+}
+
+// WithConfigDirs 设置配置目录，按顺序合并，默认为 ./conf.d
+func WithConfigDirs(dirs ...string) Option {
+	return func(l *loader) {
+		for _, dir := range dirs {
+			if dir = strings.TrimSpace(dir); dir != "" && !helper.ContainsString(l.dirs, dir) {
+				l.dirs = append(l.dirs, dir)
+			}
+		}
+	}
+}
+
+// WithConfigFiles 显式指定配置文件，在配置目录中的配置文件之后按顺序合并，不受config_names限制
+func WithConfigFiles(files ...string) Option {
+	return func(l *loader) {
+		for _, file := range files {
+			if file = strings.TrimSpace(file); file != "" && !helper.ContainsString(l.configFiles, file) {
+				l.configFiles = append(l.configFiles, file)
+			}
+		}
+	}
+}
+
+// ActiveProfiles 返回激活的Profile列表
+func ActiveProfiles() []string {
+	l := currentLoader()
+	if l == nil {
+		return []string{}
+	}
+	dst := make([]string, len(l.profiles))
+	copy(dst, l.profiles)
+	return dst
+}
+
+// IsProfileActive 判断Profile是否激活
+func IsProfileActive(profile string) bool {
+	return helper.ContainsString(ActiveProfiles(), profile)
+}
+
+func InitConfig(configNames []string, opts ...Option) error {
+	l := &loader{
+		configNames: configNames,
+		defaults:    make(map[string]interface{}),
+		overrides:   make(map[string]interface{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	v, result, err := l.load()
+	if err != nil {
+		return err
+	}
+	l.commit(v, result)
+	for _, override := range result.applied {
+		log.Infof("config %s overridden by %s", override.Key, override.Origin)
+	}
+	return nil
+}
+
+// currentLoader 当前配置使用的加载器，配置未加载时为nil
+func currentLoader() *loader {
+	rootMu.RLock()
+	defer rootMu.RUnlock()
+	return ld
+}
+
+// loader 按加载参数构建完整的配置树，首次加载与热加载使用同一个加载器
+type loader struct {
+	configNames []string
+	profiles    []string
+	dirs        []string // 配置目录，为空时使用默认目录
+	configFiles []string // 显式指定的配置文件
+	sets        []string // 命令行覆盖的配置，key=value
+	files       []string // 最近一次加载合并的配置文件
+
+	sources      []Source // 远程配置源，首次加载时创建，热加载时复用
+	extraSources []Source // 通过WithSources添加的配置源
+
+	applied []Override        // 最近一次加载生效的命令行、环境变量覆盖
+	origins map[string]origin // 最近一次加载每个Key的来源（配置文件、环境变量、命令行）
+
+	// mu 保护以下字段及files、applied、origins、sources，热加载与读取（Watch、输出配置）并发进行
+	mu        sync.Mutex
+	defaults  map[string]interface{} // 通过SetDefault设置的默认值，热加载后重新设置
+	overrides map[string]interface{} // 通过Set设置的值，热加载后重新设置
+}
+
+// loadResult 一次加载的结果，加载成功后才通过commit更新到加载器，被拒绝的热加载不影响当前状态
+type loadResult struct {
+	files   []string
+	applied []Override
+	origins map[string]origin
+	key     []byte // 本次加载读取的解密密钥
+}
+
+// commit 更新最近一次成功加载的结果，并在同一个锁内替换配置树与解密密钥，返回替换前的配置树与密钥
+func (l *loader) commit(v *viper.Viper, result *loadResult) (*viper.Viper, []byte) {
+	l.mu.Lock()
+	l.files = result.files
+	l.applied = result.applied
+	l.origins = result.origins
+	l.mu.Unlock()
+
+	rootMu.Lock()
+	defer rootMu.Unlock()
+	old, oldKey := root, secretKey
+	root, ld, secretKey = v, l, result.key
+	return old, oldKey
+}
+
+// watchedFiles 最近一次成功加载合并的配置文件
+func (l *loader) watchedFiles() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.files...)
+}
+
+// load 构建一个新的配置树，不修改当前生效的配置。合并顺序：
+//  1. 按配置目录顺序合并基础配置文件（config_names），同一目录下按文件路径字典序
+//  2. 按Profile顺序合并各配置目录下的 {name}-{profile} 配置文件
+//  3. 按顺序合并显式指定的配置文件及其同目录下的 {file}-{profile} 配置文件
+//  4. 按顺序合并远程配置源
+//  5. 应用环境变量覆盖（KAGO_前缀）、命令行覆盖（--set）
+func (l *loader) load() (*viper.Viper, *loadResult, error) {
+	// 先加载密钥，配置源的配置中也可以使用加密值；密钥在commit时才随配置树一起生效
+	key, err := loadSecretKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	v := viper.New()
+	candidates := make([]string, 0)
+	for _, dir := range l.configDirs() {
+		dirFiles, err := collectFiles(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		candidates = append(candidates, dirFiles...)
+	}
+
+	files := make([]string, 0)
+	origins := make(map[string]origin)
+	mergeFile := func(path string) error {
+		v.SetConfigFile(path)
+		if err := v.MergeInConfig(); err != nil {
+			return fmt.Errorf("failed to read config file %s: %v", path, err)
+		}
+		files = append(files, path)
+		// 单独读取文件中的Key，记录每个Key最后一次被哪个文件设置
+		fv := viper.New()
+		fv.SetConfigFile(path)
+		if err := fv.ReadInConfig(); err == nil {
+			for _, key := range fv.AllKeys() {
+				origins[key] = origin{source: SourceFile, origin: path}
+			}
+		}
+		return nil
+	}
+	merge := func(names []string) error {
+		for _, path := range candidates {
 			filename := filepath.Base(path)
 			filenameWithoutExt := strings.TrimSuffix(filename, filepath.Ext(filename)) // 去除扩展名
-			if helper.ContainsString(configNames, filenameWithoutExt) || helper.ContainsString(configNames, filename) {
-				viper.SetConfigName(filenameWithoutExt)
-				err := viper.MergeInConfig()
-				if err != nil {
-					return fmt.Errorf("failed to read config file %s: %v", path, err)
+			if helper.ContainsString(names, filenameWithoutExt) || helper.ContainsString(names, filename) {
+				if err := mergeFile(path); err != nil {
+					return err
 				}
 			}
 		}
 		return nil
-	})
+	}
+
+	if err := merge(l.configNames); err != nil {
+		return nil, nil, fmt.Errorf("Error loading config files: %v：%s\n", l.configNames, err)
+	}
+	for _, profile := range l.profiles {
+		names := make([]string, 0, len(l.configNames))
+		for _, name := range l.configNames {
+			names = append(names, name+"-"+profile)
+		}
+		if err := merge(names); err != nil {
+			return nil, nil, fmt.Errorf("Error loading profile %s config files: %v：%s\n", profile, names, err)
+		}
+	}
 
+	for _, file := range l.configFiles {
+		if _, err := os.Stat(file); err != nil {
+			return nil, nil, fmt.Errorf("config file %s not found: %w", file, err)
+		}
+		if err := mergeFile(file); err != nil {
+			return nil, nil, err
+		}
+		ext := filepath.Ext(file)
+		for _, profile := range l.profiles {
+			overlay := strings.TrimSuffix(file, ext) + "-" + profile + ext
+			if _, err := os.Stat(overlay); err != nil {
+				continue
+			}
+			if err := mergeFile(overlay); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	overrides, err := resolveOverrides(l.sets, os.Environ())
 	if err != nil {
-		return fmt.Errorf("Error loading config files: %v：%s\n", configNames, err)
+		return nil, nil, err
 	}
-	root = viper.GetViper()
-	root.AutomaticEnv()
-	return nil
+	if err := l.initSources(v, overrides, key); err != nil {
+		return nil, nil, err
+	}
+	if err := l.mergeSources(v, origins); err != nil {
+		return nil, nil, err
+	}
+	v.AutomaticEnv()
+
+	for _, override := range overrides {
+		// 合并到配置层而非viper.Set，读取上级Key（如listeners）时不会丢失同级的文件配置
+		if err := v.MergeConfigMap(override.nested()); err != nil {
+			return nil, nil, fmt.Errorf("apply config override %s: %w", override.Origin, err)
+		}
+		origins[override.Key] = origin{source: override.Source, origin: override.Origin}
+	}
+
+	l.mu.Lock()
+	for key, value := range l.defaults {
+		v.SetDefault(key, value)
+	}
+	for key, value := range l.overrides {
+		v.Set(key, value)
+	}
+	l.mu.Unlock()
+	if err := checkValues(v, key); err != nil {
+		return nil, nil, err
+	}
+	return v, &loadResult{files: files, applied: overrides, origins: origins, key: key}, nil
+}
+
+func (l *loader) configDirs() []string {
+	if len(l.dirs) == 0 {
+		return []string{constant.DefaultConfigPath}
+	}
+	return l.dirs
+}
+
+// collectFiles 递归获取目录下的所有文件，按路径字典序返回。
+// 跟随符号链接，跳过以"."开头的文件和目录：Kubernetes ConfigMap挂载目录中，
+// 配置文件是指向 ..data/{file} 的符号链接，..data 及 ..{timestamp} 目录不能重复加载
+func collectFiles(dir string) ([]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("config directory %s does not exist", dir)
+		}
+		return nil, fmt.Errorf("config directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("config directory %s is not a directory", dir)
+	}
+
+	files := make([]string, 0)
+	visited := make(map[string]bool)
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		// 防止符号链接形成环
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return fmt.Errorf("config directory %s: %w", dir, err)
+		}
+		if visited[real] {
+			return nil
+		}
+		visited[real] = true
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("read config directory %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			info, err := os.Stat(path)
+			if err != nil {
+				// 悬空的符号链接
+				return fmt.Errorf("config file %s: %w", path, err)
+			}
+			if info.IsDir() {
+				if err := walk(path); err != nil {
+					return err
+				}
+				continue
+			}
+			files = append(files, path)
+		}
+		return nil
+	}
+	if err := walk(dir); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (l *loader) setDefault(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.defaults[key] = value
+}
+
+func (l *loader) set(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.overrides[key] = value
 }
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigs 在临时目录中写入配置文件，返回目录
+func writeConfigs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// initTestConfig 使用临时目录中的 application.yml 加载配置
+func initTestConfig(t *testing.T, application string, opts ...Option) error {
+	t.Helper()
+	dir := writeConfigs(t, map[string]string{"application.yml": application})
+	return InitConfig([]string{"application"}, append([]Option{WithConfigDirs(dir)}, opts...)...)
+}
+
+// mustInitTestConfig 加载配置，失败时终止测试
+func mustInitTestConfig(t *testing.T, application string, opts ...Option) {
+	t.Helper()
+	if err := initTestConfig(t, application, opts...); err != nil {
+		t.Fatalf("init config: %v", err)
+	}
+}
+
+// effective 生效的配置项，按Key索引
+func effective(prefix string) map[string]Entry {
+	entries := make(map[string]Entry)
+	for _, entry := range Effective(prefix) {
+		entries[entry.Key] = entry
+	}
+	return entries
+}
+
+func TestMergeOrder(t *testing.T) {
+	dir1 := writeConfigs(t, map[string]string{
+		"application.yml":       "m:\n  k1: dir1\n  k2: dir1\n  k3: dir1\n  k4: dir1\n  k5: dir1\n  k6: dir1\n  k7: dir1\n",
+		"application-dev.yml":   "m:\n  k3: dir1-dev\n  k4: dir1-dev\n  k5: dir1-dev\n  k6: dir1-dev\n  k7: dir1-dev\n",
+		"application-local.yml": "m:\n  k5: dir1-local\n  k6: dir1-local\n  k7: dir1-local\n",
+		"other.yml":             "m:\n  k1: other\n",
+	})
+	dir2 := writeConfigs(t, map[string]string{
+		"application.yml":     "m:\n  k2: dir2\n  k3: dir2\n  k4: dir2\n  k5: dir2\n  k6: dir2\n  k7: dir2\n",
+		"application-dev.yml": "m:\n  k4: dir2-dev\n  k5: dir2-dev\n  k6: dir2-dev\n  k7: dir2-dev\n",
+	})
+	extra := writeConfigs(t, map[string]string{
+		"custom.yml":     "m:\n  k6: custom\n  k7: custom\n",
+		"custom-dev.yml": "m:\n  k7: custom-dev\n",
+	})
+	custom := filepath.Join(extra, "custom.yml")
+	err := InitConfig([]string{"application"},
+		WithConfigDirs(dir1, dir2), WithProfiles("dev", "local"), WithConfigFiles(custom))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 基础配置按目录顺序 < Profile按顺序 < 显式指定的配置文件及其Profile配置
+	tests := []struct {
+		key    string
+		want   string
+		origin string
+	}{
+		{"m.k1", "dir1", filepath.Join(dir1, "application.yml")},
+		{"m.k2", "dir2", filepath.Join(dir2, "application.yml")},
+		{"m.k3", "dir1-dev", filepath.Join(dir1, "application-dev.yml")},
+		{"m.k4", "dir2-dev", filepath.Join(dir2, "application-dev.yml")},
+		{"m.k5", "dir1-local", filepath.Join(dir1, "application-local.yml")},
+		{"m.k6", "custom", custom},
+		{"m.k7", "custom-dev", filepath.Join(extra, "custom-dev.yml")},
+	}
+	entries := effective("m")
+	for _, tt := range tests {
+		entry := entries[tt.key]
+		if entry.Value != tt.want || entry.Source != SourceFile || entry.Origin != tt.origin {
+			t.Errorf("%s = %+v, want %s from %s", tt.key, entry, tt.want, tt.origin)
+		}
+	}
+	if len(entries) != len(tests) {
+		t.Fatalf("entries = %v, files not in config_names must not be merged", entries)
+	}
+}
+
+func TestOverridePrecedence(t *testing.T) {
+	t.Setenv("KAGO_APP__ENV", "env")
+	t.Setenv("KAGO_APP__FLAG", "env")
+	t.Setenv("KAGO_APP__SET", "env")
+	t.Setenv("KAGO_APP__ADDED", "env")
+	dir := writeConfigs(t, map[string]string{
+		"application.yml": "app:\n  file: file\n  env: file\n  flag: file\n  set: file\n",
+	})
+	err := InitConfig([]string{"application"},
+		WithConfigDirs(dir), WithSets("app.flag=flag", "app.set=flag"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	GetWrapper("app").Set("set", "set")
+
+	// 配置文件 < KAGO_环境变量 < --set < Set，热加载后保持不变
+	for _, reload := range []bool{false, true} {
+		if reload {
+			if err := Reload(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tests := []struct {
+			key    string
+			want   string
+			source string
+			origin string
+		}{
+			{"app.file", "file", SourceFile, filepath.Join(dir, "application.yml")},
+			{"app.env", "env", SourceEnv, "KAGO_APP__ENV"},
+			{"app.added", "env", SourceEnv, "KAGO_APP__ADDED"},
+			{"app.flag", "flag", SourceFlag, "--set app.flag"},
+			{"app.set", "set", SourceSet, ""},
+		}
+		entries := effective("app")
+		for _, tt := range tests {
+			if got := GetWrapper("app").GetString(strings.TrimPrefix(tt.key, "app.")); got != tt.want {
+				t.Errorf("reload=%v: %s = %q, want %q", reload, tt.key, got, tt.want)
+			}
+			entry := entries[tt.key]
+			if entry.Source != tt.source || entry.Origin != tt.origin {
+				t.Errorf("reload=%v: %s source = %s %q, want %s %q", reload, tt.key, entry.Source, entry.Origin, tt.source, tt.origin)
+			}
+		}
+	}
+
+	for _, o := range Overrides() {
+		if o.Key == "app.flag" && (len(o.Shadowed) != 1 || o.Shadowed[0] != "KAGO_APP__FLAG") {
+			t.Fatalf("app.flag override = %+v, want KAGO_APP__FLAG shadowed", o)
+		}
+	}
+}
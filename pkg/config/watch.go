@@ -0,0 +1,251 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// reloadDebounce 配置变更后等待的时间，合并编辑器、ConfigMap更新、配置中心发布时产生的多次事件
+const reloadDebounce = 500 * time.Millisecond
+
+type (
+	// ChangeListener 配置变更回调，old、new分别为变更前后命名空间的配置快照
+	ChangeListener func(old, new *Configuration)
+
+	// Logger 配置组件使用的日志接口。配置组件先于日志组件初始化，默认输出到标准错误，不影响子命令的输出
+	Logger interface {
+		Infof(format string, args ...interface{})
+		Warnf(format string, args ...interface{})
+		Errorf(format string, args ...interface{})
+	}
+
+	subscription struct {
+		prefix   string
+		listener ChangeListener
+	}
+
+	stdLogger struct{}
+)
+
+var (
+	log Logger = stdLogger{}
+
+	subscriptionsMu sync.RWMutex
+	subscriptions   []subscription
+
+	watcherMu sync.Mutex
+	watcher   *fsnotify.Watcher
+
+	// reloadMu 保证同一时间只有一个热加载
+	reloadMu sync.Mutex
+
+	reloadTimerMu sync.Mutex
+	reloadTimer   *time.Timer
+)
+
+func (stdLogger) Infof(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "[config] "+format+"\n", args...)
+}
+
+func (stdLogger) Warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "[config] WARN "+format+"\n", args...)
+}
+
+func (stdLogger) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "[config] ERROR "+format+"\n", args...)
+}
+
+// SetLogger 设置配置组件使用的日志，日志组件初始化后替换
+func SetLogger(l Logger) {
+	log = l
+}
+
+// GetLogger 获取配置组件使用的日志，供配置源等扩展使用
+func GetLogger() Logger {
+	return log
+}
+
+// OnChange 订阅指定前缀（命名空间）下的配置变更，prefix为空时订阅所有配置
+func OnChange(prefix string, listener ChangeListener) {
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	subscriptions = append(subscriptions, subscription{prefix: prefix, listener: listener})
+}
+
+// OnChange 订阅当前配置实例（命名空间）下的配置变更
+func (c *Configuration) OnChange(listener ChangeListener) {
+	OnChange(c.namespace, listener)
+}
+
+// Snapshot 返回当前配置实例的快照，热加载不会改变快照中的配置
+func (c *Configuration) Snapshot() *Configuration {
+	v, key := c.tree()
+	return &Configuration{
+		namespace: c.namespace,
+		snapshot:  v,
+		key:       key,
+		alias:     c.alias,
+	}
+}
+
+// Watch 监听所有已合并配置文件所在的目录及远程配置源，变更时重新加载整个配置树
+func Watch() error {
+	IsInitialized()
+	watcherMu.Lock()
+	defer watcherMu.Unlock()
+	if watcher != nil {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("config watch: %w", err)
+	}
+	l := currentLoader()
+	for _, dir := range watchDirs(l.watchedFiles()) {
+		if err := w.Add(dir); err != nil {
+			w.Close()
+			return fmt.Errorf("config watch %s: %w", dir, err)
+		}
+		log.Infof("watching config directory: %s", dir)
+	}
+	watcher = w
+	go watchLoop(w)
+	watchSources(l)
+	return nil
+}
+
+// StopWatch 停止监听配置文件
+func StopWatch() error {
+	watcherMu.Lock()
+	defer watcherMu.Unlock()
+	if watcher == nil {
+		return nil
+	}
+	if stopSources != nil {
+		stopSources()
+		stopSources = nil
+	}
+	err := watcher.Close()
+	watcher = nil
+	return err
+}
+
+func (l *ViperLifecycle) Title() string {
+	return "config"
+}
+
+func (l *ViperLifecycle) OnDestroy(ctx context.Context) error {
+	return StopWatch()
+}
+
+func NewViperLifecycle() *ViperLifecycle {
+	return new(ViperLifecycle)
+}
+
+// watchDirs 配置文件所在的目录（去重、排序）。监听目录而非文件，才能感知编辑器替换文件、ConfigMap的符号链接切换
+func watchDirs(files []string) []string {
+	dirs := make([]string, 0, len(files))
+	seen := make(map[string]bool)
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func watchLoop(w *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			scheduleReload()
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Errorf("config watch error: %v", err)
+		}
+	}
+}
+
+// scheduleReload 延迟reloadDebounce后重新加载，期间的多次变更只加载一次
+func scheduleReload() {
+	reloadTimerMu.Lock()
+	defer reloadTimerMu.Unlock()
+	if reloadTimer != nil {
+		reloadTimer.Stop()
+	}
+	reloadTimer = time.AfterFunc(reloadDebounce, func() {
+		if err := Reload(); err != nil {
+			log.Errorf("reload config rejected, keep previous config: %v", err)
+		}
+	})
+}
+
+// Reload 重新加载所有配置文件，加载失败时保留当前配置并返回错误；
+// 加载成功后原子替换配置树，并通知配置发生变化的订阅者
+func Reload() error {
+	IsInitialized()
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	l := currentLoader()
+	v, result, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	old, oldKey := l.commit(v, result)
+	log.Infof("config reloaded from %v", result.files)
+
+	notify(old, v, oldKey, result.key)
+	return nil
+}
+
+// notify 通知配置发生变化的订阅者，回调panic不影响其他订阅者
+func notify(old, new *viper.Viper, oldKey, newKey []byte) {
+	subscriptionsMu.RLock()
+	subs := make([]subscription, len(subscriptions))
+	copy(subs, subscriptions)
+	subscriptionsMu.RUnlock()
+
+	for _, sub := range subs {
+		if reflect.DeepEqual(settingsOf(old, sub.prefix), settingsOf(new, sub.prefix)) {
+			continue
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("config change listener for %q panic: %v", sub.prefix, r)
+				}
+			}()
+			sub.listener(
+				&Configuration{namespace: sub.prefix, snapshot: old, key: oldKey, alias: make(map[string]string)},
+				&Configuration{namespace: sub.prefix, snapshot: new, key: newKey, alias: make(map[string]string)},
+			)
+		}()
+	}
+}
+
+func settingsOf(v *viper.Viper, prefix string) interface{} {
+	if prefix == "" {
+		return v.AllSettings()
+	}
+	return v.Get(prefix)
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{"application.yml": "app:\n  name: v1\n"})
+	if err := InitConfig([]string{"application"}, WithConfigDirs(dir)); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "application.yml")
+	app := GetWrapper("app")
+
+	var mu sync.Mutex
+	changes := 0
+	OnChange("app", func(old, new *Configuration) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes++
+	})
+
+	rejected := []struct {
+		name    string
+		content string
+	}{
+		{"invalid yaml", "app:\n  name: [v2\n  added: x\n"},
+		{"placeholder cycle", "app:\n  name: v2\n  added: x\n  a: ${app.b}\n  b: ${app.a}\n"},
+	}
+	for _, tt := range rejected {
+		writeFile(t, file, tt.content)
+		if err := Reload(); err == nil {
+			t.Fatalf("%s: reload must be rejected", tt.name)
+		}
+		if got := app.GetString("name"); got != "v1" {
+			t.Fatalf("%s: app.name = %q, previous config must be kept", tt.name, got)
+		}
+		if source, _ := sourceOf("app.added"); source != "" {
+			t.Fatalf("%s: rejected reload must not record origins, app.added from %q", tt.name, source)
+		}
+	}
+
+	writeFile(t, file, "app:\n  name: v2\n  added: x\n")
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := app.GetString("name"); got != "v2" {
+		t.Fatalf("app.name = %q after reload, want v2", got)
+	}
+	if source, origin := sourceOf("app.added"); source != SourceFile || origin != file {
+		t.Fatalf("app.added from %s %s, want %s %s", source, origin, SourceFile, file)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if changes != 1 {
+		t.Fatalf("listener notified %d times, want 1", changes)
+	}
+}
+
+// TestReloadConcurrentWithWatch 在 -race 下检查热加载与监听、读取加载器状态之间没有数据竞争
+func TestReloadConcurrentWithWatch(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{"application.yml": "app:\n  name: v1\n"})
+	if err := InitConfig([]string{"application"}, WithConfigDirs(dir)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		StopWatch()
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			if err := Reload(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			if err := Watch(); err != nil {
+				t.Error(err)
+				return
+			}
+			Effective("app")
+			Overrides()
+			if err := StopWatch(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -6,9 +6,29 @@ const (
 	DefaultLogConfigName = "./conf.d/logger.yml"
 	EnvLogConfigName     = "LOG_CONFIG_NAME"
 
+	Profile    = "profile"
+	EnvProfile = "APP_PROFILE"
+
 	ConfigNames       = "config_names"
 	EnvConfigNames    = "CONFIG_NAMES"
 	DefaultConfigPath = "./conf.d"
+
+	// ConfigDir、ConfigFile 与config_names一致使用下划线，兼容 --config-dir、--config-file
+	ConfigDir      = "config_dir"
+	EnvConfigDirs  = "CONFIG_DIRS"
+	ConfigFile     = "config_file"
+	EnvConfigFiles = "CONFIG_FILES"
+
+	// Set 命令行覆盖配置，如：--set listeners.web.port=8080
+	Set = "set"
+	// EnvPrefix 覆盖配置的环境变量前缀，"__"分隔层级，如：KAGO_LISTENERS__WEB__PORT=8080
+	EnvPrefix       = "KAGO_"
+	EnvKeySeparator = "__"
+
+	// EnvConfigSecretKey 配置解密密钥（base64），ENC(...)值使用AES-GCM解密
+	EnvConfigSecretKey = "CONFIG_SECRET_KEY"
+	// EnvConfigSecretKeyFile 配置解密密钥文件，未设置CONFIG_SECRET_KEY时读取
+	EnvConfigSecretKeyFile = "CONFIG_SECRET_KEY_FILE"
 )
 
 // 配置文件
@@ -36,3 +56,25 @@ const (
 const (
 	DubboConsumerConfig = "dubbo-consumer-config"
 )
+
+// 配置组件自身的配置
+const (
+	ConfigConfig = "config"
+)
+
+// 日志配置，合并后的配置中存在时覆盖日志配置文件
+const (
+	LoggerConfig = "logger"
+)
+
+// 生命周期配置
+const (
+	LifecycleConfig = "lifecycle"
+)
+
+// 监听器配置
+const (
+	ListenersConfig = "listeners"
+	DefaultListener = "web"
+	AdminListener   = "admin"
+)
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"errors"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"io/fs"
+)
+
+// loadConfig 读取日志配置：以日志配置文件为基础，合并后的配置中存在 logger 命名空间时覆盖其中的 default、rolling、levels、sinks，
+// 从而支持Profile、占位符（如 #{LOG_DIR:./logs}）与环境变量覆盖；存在 logger 命名空间时日志配置文件可以不存在
+func loadConfig(filename string) (ConfigWrapper, error) {
+	wrapper, err := readConfig(filename)
+	if !fromConfig() {
+		return wrapper, err
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return wrapper, err
+	}
+	return overlayConfig(wrapper)
+}
+
+// fromConfig 合并后的配置中是否存在 logger 命名空间
+func fromConfig() bool {
+	return config.Loaded() && len(config.GetWrapper(constant.LoggerConfig).Keys()) > 0
+}
+
+// overlayConfig 使用 logger 命名空间覆盖日志配置文件，未配置的项保留日志配置文件中的值
+func overlayConfig(wrapper ConfigWrapper) (ConfigWrapper, error) {
+	c := config.GetWrapper(constant.LoggerConfig)
+	if c.IsSet("default") {
+		if err := config.Bind(config.MakeKey(constant.LoggerConfig, "default"), &wrapper.Default); err != nil {
+			return wrapper, err
+		}
+	}
+	if c.IsSet("rolling") {
+		if err := config.Bind(config.MakeKey(constant.LoggerConfig, "rolling"), &wrapper.Rolling); err != nil {
+			return wrapper, err
+		}
+	}
+	if c.IsSet("sinks") {
+		var sinks struct {
+			Sinks []SinkConfig `json:"sinks"`
+		}
+		if err := c.Bind(&sinks); err != nil {
+			return wrapper, err
+		}
+		wrapper.Sinks = sinks.Sinks
+	}
+	// 名称中包含"."（如 payment.refund），按展开后的Key读取，不能绑定为字典
+	levels := config.GetWrapper(config.MakeKey(constant.LoggerConfig, "levels"))
+	for _, name := range levels.Keys() {
+		if wrapper.Levels == nil {
+			wrapper.Levels = make(map[string]string)
+		}
+		wrapper.Levels[name] = levels.GetString(name)
+	}
+	return wrapper, nil
+}
@@ -0,0 +1,257 @@
+package logger
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rootLogger 根日志的名称，未配置级别的命名日志继承根日志的级别
+const rootLogger = ""
+
+var (
+	// atomicLevel 根日志的级别，所有输出共享，运行时修改立即生效
+	atomicLevel = zap.NewAtomicLevel()
+
+	levelMu sync.Mutex
+	// levels 根日志与命名日志的级别，key为日志名称
+	levels = map[string]*levelEntry{rootLogger: {configured: levelPtr(zapcore.InfoLevel)}}
+	// namedLevels 命名日志生效级别的快照（map[string]zapcore.Level），记录日志时无锁读取
+	namedLevels atomic.Value
+)
+
+type (
+	levelEntry struct {
+		configured *zapcore.Level // 配置文件中的级别，nil表示继承上级
+		override   *zapcore.Level // 运行时修改的级别
+		timer      *time.Timer    // 临时修改到期后恢复
+		expiresAt  time.Time
+	}
+
+	// LevelStatus 日志级别状态
+	LevelStatus struct {
+		Level      string `json:"level"`
+		Configured string `json:"configured,omitempty"`
+		// ExpiresAt 临时修改的日志级别到期时间，到期后恢复为Configured
+		ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+		// Loggers 配置或修改过级别的命名日志，未列出的命名日志继承上级的级别
+		Loggers map[string]LevelStatus `json:"loggers,omitempty"`
+	}
+)
+
+func init() {
+	namedLevels.Store(map[string]zapcore.Level{})
+}
+
+// Level 根日志当前生效的日志级别
+func Level() zapcore.Level {
+	return atomicLevel.Level()
+}
+
+// NamedLevel 命名日志生效的日志级别，按"."逐级向上继承，如：payment.refund 未配置时继承 payment
+func NamedLevel(name string) zapcore.Level {
+	named := namedLevels.Load().(map[string]zapcore.Level)
+	for name != rootLogger && len(named) > 0 {
+		if level, ok := named[name]; ok {
+			return level
+		}
+		idx := strings.LastIndexByte(name, '.')
+		if idx < 0 {
+			break
+		}
+		name = name[:idx]
+	}
+	return atomicLevel.Level()
+}
+
+// GetLevelStatus 获取根日志与命名日志的级别状态
+func GetLevelStatus() LevelStatus {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	status := levels[rootLogger].status(atomicLevel.Level())
+	for name, entry := range levels {
+		if name == rootLogger {
+			continue
+		}
+		if status.Loggers == nil {
+			status.Loggers = make(map[string]LevelStatus)
+		}
+		status.Loggers[name] = entry.status(NamedLevel(name))
+	}
+	return status
+}
+
+// SetLevel 运行时修改根日志的级别，ttl大于0时到期后恢复为配置文件中的级别。
+// 配置文件中的级别变更时以配置文件为准，并取消所有临时修改
+func SetLevel(level zapcore.Level, ttl time.Duration) {
+	SetNamedLevel(rootLogger, level, ttl)
+}
+
+// SetNamedLevel 运行时修改命名日志的级别，下级日志未单独配置时一同生效；ttl大于0时到期后恢复
+func SetNamedLevel(name string, level zapcore.Level, ttl time.Duration) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	entry := levels[name]
+	if entry == nil {
+		entry = &levelEntry{}
+		levels[name] = entry
+	}
+	entry.stopRevert()
+	entry.override = levelPtr(level)
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() {
+			levelMu.Lock()
+			defer levelMu.Unlock()
+			// 已被新的修改替换
+			if entry.timer != timer {
+				return
+			}
+			entry.timer = nil
+			entry.override = nil
+			if name != rootLogger && entry.configured == nil {
+				delete(levels, name)
+			}
+			applyLevels()
+			baseLogger.Sugar().Infow("log level reverted", "name", name, "level", NamedLevel(name).String())
+		})
+		entry.timer = timer
+	}
+	applyLevels()
+}
+
+// ResetLevel 取消运行时修改，恢复为配置文件中的级别（命名日志未配置时继承上级）
+func ResetLevel(name string) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	entry, ok := levels[name]
+	if !ok {
+		return
+	}
+	entry.stopRevert()
+	entry.override = nil
+	if name != rootLogger && entry.configured == nil {
+		delete(levels, name)
+	}
+	applyLevels()
+}
+
+// setConfiguredLevels 应用配置文件中的日志级别，取消所有运行时修改
+func setConfiguredLevels(root zapcore.Level, named map[string]zapcore.Level) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	for _, entry := range levels {
+		entry.stopRevert()
+	}
+	levels = map[string]*levelEntry{rootLogger: {configured: levelPtr(root)}}
+	for name, level := range named {
+		levels[name] = &levelEntry{configured: levelPtr(level)}
+	}
+	applyLevels()
+}
+
+// configuredLevelsChanged 配置文件中的级别是否与当前配置不同
+func configuredLevelsChanged(root zapcore.Level, named map[string]zapcore.Level) bool {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	current := make(map[string]zapcore.Level)
+	for name, entry := range levels {
+		if name != rootLogger && entry.configured != nil {
+			current[name] = *entry.configured
+		}
+	}
+	return *levels[rootLogger].configured != root || !reflect.DeepEqual(current, named)
+}
+
+// applyLevels 根据配置与运行时修改计算生效的级别，调用方持有levelMu
+func applyLevels() {
+	root := levels[rootLogger]
+	atomicLevel.SetLevel(root.effective(zapcore.InfoLevel))
+	named := make(map[string]zapcore.Level)
+	for name, entry := range levels {
+		if name == rootLogger {
+			continue
+		}
+		if entry.override != nil || entry.configured != nil {
+			named[name] = entry.effective(zapcore.InfoLevel)
+		}
+	}
+	namedLevels.Store(named)
+}
+
+// minLevel 根日志与所有命名日志中最低的级别，用于快速判断是否需要记录
+func minLevel() zapcore.Level {
+	min := atomicLevel.Level()
+	for _, level := range namedLevels.Load().(map[string]zapcore.Level) {
+		if level < min {
+			min = level
+		}
+	}
+	return min
+}
+
+func (e *levelEntry) effective(def zapcore.Level) zapcore.Level {
+	if e.override != nil {
+		return *e.override
+	}
+	if e.configured != nil {
+		return *e.configured
+	}
+	return def
+}
+
+func (e *levelEntry) status(level zapcore.Level) LevelStatus {
+	status := LevelStatus{Level: level.String()}
+	if e.configured != nil {
+		status.Configured = e.configured.String()
+	}
+	if e.timer != nil {
+		t := e.expiresAt
+		status.ExpiresAt = &t
+	}
+	return status
+}
+
+func (e *levelEntry) stopRevert() {
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+}
+
+// levelOf 配置中的日志级别，未配置时为info
+func levelOf(config zap.Config) zapcore.Level {
+	if config.Level == (zap.AtomicLevel{}) {
+		return zapcore.InfoLevel
+	}
+	return config.Level.Level()
+}
+
+// parseNamedLevels 解析配置文件中按名称配置的日志级别
+func parseNamedLevels(configured map[string]string) (map[string]zapcore.Level, error) {
+	named := make(map[string]zapcore.Level, len(configured))
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		level, err := zapcore.ParseLevel(configured[name])
+		if err != nil {
+			return nil, fmt.Errorf("logger %s: %w", name, err)
+		}
+		named[name] = level
+	}
+	return named, nil
+}
+
+func levelPtr(level zapcore.Level) *zapcore.Level {
+	return &level
+}
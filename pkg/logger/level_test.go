@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+	"time"
+)
+
+// withLevels 应用配置的日志级别，并将日志输出到observer，测试结束后恢复
+func withLevels(t *testing.T, named map[string]zapcore.Level) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zapcore.DebugLevel)
+	saved := baseLogger
+	baseLogger = zap.New(newLevelFilterCore(core))
+	setConfiguredLevels(zapcore.InfoLevel, named)
+	t.Cleanup(func() {
+		setConfiguredLevels(zapcore.InfoLevel, nil)
+		baseLogger = saved
+	})
+	return logs
+}
+
+func TestNamedLevelInheritance(t *testing.T) {
+	logs := withLevels(t, map[string]zapcore.Level{
+		"payment":        zapcore.DebugLevel,
+		"payment.refund": zapcore.WarnLevel,
+	})
+	tests := []struct {
+		name string
+		want zapcore.Level
+	}{
+		{"", zapcore.InfoLevel},
+		{"order", zapcore.InfoLevel},
+		{"payment", zapcore.DebugLevel},
+		{"payment.x", zapcore.DebugLevel},
+		{"payment.x.y", zapcore.DebugLevel},
+		{"paymentx", zapcore.InfoLevel},
+		{"payment.refund", zapcore.WarnLevel},
+		{"payment.refund.partial", zapcore.WarnLevel},
+	}
+	for _, tt := range tests {
+		if got := NamedLevel(tt.name); got != tt.want {
+			t.Errorf("NamedLevel(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	Named("payment.x").Debug("inherited")
+	Named("payment.refund").Info("overridden")
+	Named("order").Debug("root")
+	if got := logs.FilterMessage("inherited").Len(); got != 1 {
+		t.Errorf("payment.x debug written %d times, want inherited debug level", got)
+	}
+	if got := logs.Len(); got != 1 {
+		t.Errorf("got %d entries, more specific levels must win: %v", got, logs.All())
+	}
+
+	// 运行时修改上级日志的级别，单独配置了级别的下级不受影响
+	SetNamedLevel("payment", zapcore.ErrorLevel, 0)
+	if got := NamedLevel("payment.x"); got != zapcore.ErrorLevel {
+		t.Errorf("payment.x after override = %s, want error", got)
+	}
+	if got := NamedLevel("payment.refund"); got != zapcore.WarnLevel {
+		t.Errorf("payment.refund after parent override = %s, want warn", got)
+	}
+}
+
+func TestNamedLevelTTLRevert(t *testing.T) {
+	withLevels(t, map[string]zapcore.Level{"payment": zapcore.DebugLevel})
+
+	SetNamedLevel("payment", zapcore.ErrorLevel, 50*time.Millisecond)
+	SetNamedLevel("order", zapcore.DebugLevel, 50*time.Millisecond)
+	SetNamedLevel("stock", zapcore.WarnLevel, 50*time.Millisecond)
+	// 新的修改替换临时修改，不再到期恢复
+	SetNamedLevel("stock", zapcore.ErrorLevel, 0)
+	if got := NamedLevel("payment.x"); got != zapcore.ErrorLevel {
+		t.Fatalf("payment.x = %s, want error before expiry", got)
+	}
+	if status := GetLevelStatus().Loggers["payment"]; status.ExpiresAt == nil || status.Configured != "debug" {
+		t.Fatalf("payment status = %+v, want expiry and configured debug", status)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for NamedLevel("payment") != zapcore.DebugLevel || NamedLevel("order") != zapcore.InfoLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("levels were not reverted: payment = %s, order = %s", NamedLevel("payment"), NamedLevel("order"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := NamedLevel("payment.x"); got != zapcore.DebugLevel {
+		t.Fatalf("payment.x after revert = %s, want configured debug", got)
+	}
+	status := GetLevelStatus()
+	if s := status.Loggers["payment"]; s.ExpiresAt != nil || s.Level != "debug" {
+		t.Fatalf("payment status after revert = %+v", s)
+	}
+	if _, ok := status.Loggers["order"]; ok {
+		t.Fatal("order was not configured and must be removed after revert")
+	}
+	if got := NamedLevel("stock"); got != zapcore.ErrorLevel {
+		t.Fatalf("stock = %s, replaced override must not be reverted", got)
+	}
+}
@@ -3,23 +3,21 @@ package logger
 import (
 	"context"
 	"fmt"
-	"github.com/chnyangzhen/kago-fly/pkg/constant"
-	"github.com/natefinch/lumberjack"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
-	"os"
+	"sort"
+	"time"
 )
 
 type LogLifecycle int
 
 func (l *LogLifecycle) OnDestroy(ctx context.Context) error {
+	StopWatch()
 	baseLogger.Sync()
 	baseLogger.Sugar().Sync()
-	errorFileWriter.Close()
-	warnFileWriter.Close()
-	infoFileWriter.Close()
+	closeSinks()
 	return nil
 }
 
@@ -32,16 +30,21 @@ func NewLogLifecycle() *LogLifecycle {
 }
 
 var (
-	baseLogger                                      *zap.Logger
-	errorFileWriter, warnFileWriter, infoFileWriter *lumberjack.Logger
-	consoleWriter                                   = zapcore.Lock(os.Stdout)
+	baseLogger *zap.Logger
+	// closeSinks 关闭输出规则、outputPaths、errorOutputPaths打开的文件
+	closeSinks = func() {}
 )
 
 type ConfigWrapper struct {
 	Default zap.Config        `json:"default" yaml:"default"`
 	Rolling RollingFileConfig `json:"rolling" yaml:"rolling"`
+	// Levels 按名称配置命名日志的级别，如：payment: debug
+	Levels map[string]string `json:"levels" yaml:"levels"`
+	// Sinks 日志输出规则，配置后替代rolling中的info、warn、error文件
+	Sinks []SinkConfig `json:"sinks" yaml:"sinks"`
 }
 
+// RollingFileConfig 滚动日志文件配置，未配置sinks时按级别输出到info、warn、error文件，滚动参数作为sinks的默认值
 type RollingFileConfig struct {
 	LogFilePath   string `json:"logFilePath" yaml:"logFilePath"`     // 日志路径
 	ErrorFileName string `json:"errorFileName" yaml:"errorFileName"` // 默认名称：error.log
@@ -51,82 +54,147 @@ type RollingFileConfig struct {
 	MaxBackups    int    `json:"maxBackups" yaml:"maxBackups"`       // MaxBackups是要保留的最大旧日志文件数
 	MaxAge        int    `json:"maxAge" yaml:"maxAge"`               // MaxAge是根据日期保留旧日志文件的最大天数
 	Compress      bool   `json:"compress" yaml:"compress"`           // 是否压缩
+	Encoding      string `json:"encoding" yaml:"encoding"`           // 编码：json、console，默认与default.encoding一致
 }
 
+// InitLogger 初始化日志组件，合并后的配置中存在 logger 命名空间时覆盖日志配置文件filename中的配置
 func InitLogger(filename string) error {
-	file, err := ioutil.ReadFile(filename)
+	config, err := loadConfig(filename)
 	if err != nil {
-		return fmt.Errorf("read config(file:%s): %w", filename, err)
+		return err
 	}
+	return buildLogger(config)
+}
 
+func readConfig(filename string) (ConfigWrapper, error) {
 	config := ConfigWrapper{
 		Default: zap.Config{},
 		Rolling: RollingFileConfig{},
 	}
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return config, fmt.Errorf("read config(file:%s): %w", filename, err)
+	}
 	err = yaml.Unmarshal(file, &config)
 	if err != nil {
-		return fmt.Errorf("unmarshal config: %w", err)
+		return config, fmt.Errorf("unmarshal config: %w", err)
 	}
-
-	return buildLogger(config)
+	return config, nil
 }
 
 func buildLogger(wrapper ConfigWrapper) error {
 
 	config := wrapper.Default
-	rollingConfig := wrapper.Rolling
-
-	logEncoder := zapcore.NewJSONEncoder(config.EncoderConfig)
 
-	infoFileWriter = initLumberjackLogger(rollingConfig.InfoFileName, rollingConfig)
-	infoLevel := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level == zapcore.InfoLevel && level-zapcore.InfoLevel-config.Level.Level() > -1
-	})
-
-	warnFileWriter = initLumberjackLogger(rollingConfig.WarnFileName, rollingConfig)
-	warnLevel := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level == zapcore.WarnLevel && zapcore.WarnLevel-config.Level.Level() > -1
-	})
-
-	errorFileWriter = initLumberjackLogger(rollingConfig.ErrorFileName, rollingConfig)
-	errorLevel := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level > zapcore.WarnLevel && zapcore.WarnLevel-config.Level.Level() > -1
-	})
-
-	consoleLevel := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level-config.Level.Level() > -1
-	})
+	// 输出路径（默认stdout）使用default.encoding，滚动日志文件未配置编码时与其一致
+	outputEncoder, err := newEncoder(config.Encoding, config.EncoderConfig)
+	if err != nil {
+		return err
+	}
 
-	zapCores := []zapcore.Core{
-		zapcore.NewCore(logEncoder, zapcore.AddSync(infoFileWriter), infoLevel),
-		zapcore.NewCore(logEncoder, zapcore.AddSync(warnFileWriter), warnLevel),
-		zapcore.NewCore(logEncoder, zapcore.AddSync(errorFileWriter), errorLevel),
-		zapcore.NewCore(logEncoder, consoleWriter, consoleLevel),
+	outputPaths := config.OutputPaths
+	if len(outputPaths) == 0 {
+		outputPaths = []string{"stdout"}
+	}
+	outputs, closeOutputs, err := zap.Open(outputPaths...)
+	if err != nil {
+		return fmt.Errorf("open output paths: %w", err)
+	}
+	errorOutputPaths := config.ErrorOutputPaths
+	if len(errorOutputPaths) == 0 {
+		errorOutputPaths = []string{"stderr"}
+	}
+	errorOutputs, closeErrorOutputs, err := zap.Open(errorOutputPaths...)
+	if err != nil {
+		closeOutputs()
+		return fmt.Errorf("open error output paths: %w", err)
 	}
 
-	l, err := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
-		return zapcore.NewTee(zapCores...)
-	}))
+	closers := []func(){closeOutputs, closeErrorOutputs}
+	closeAll := func() {
+		for _, closer := range closers {
+			closer()
+		}
+	}
 
+	namedLevels, err := parseNamedLevels(wrapper.Levels)
 	if err != nil {
+		closeAll()
 		return err
 	}
 
-	baseLogger = l
+	// 日志级别由levelFilterCore按名称过滤，以下只按输出规则分发
+	zapCores := make([]zapcore.Core, 0)
+	for _, sink := range sinksOf(wrapper) {
+		core, closer, err := newSinkCore(sink, wrapper)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		zapCores = append(zapCores, core)
+		closers = append(closers, closer)
+	}
+	outputLevel := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+		return true
+	})
+	zapCores = append(zapCores, sampled(newRedactCore(zapcore.NewCore(outputEncoder, outputs, outputLevel)), config.Sampling))
+
+	setConfiguredLevels(levelOf(config), namedLevels)
+	baseLogger = zap.New(newLevelFilterCore(zapcore.NewTee(zapCores...)), buildOptions(config, errorOutputs)...)
+	closeSinks = closeAll
 
 	return nil
 }
 
-func initLumberjackLogger(filename string, fileConfig RollingFileConfig) *lumberjack.Logger {
-	// 创建info级别的lumberjack logger实例
-	lumberjackLogger := &lumberjack.Logger{
-		Filename:   fileConfig.LogFilePath + constant.FilepathSeparator + filename,
-		MaxSize:    fileConfig.MaxSize,
-		MaxBackups: fileConfig.MaxBackups,
-		MaxAge:     fileConfig.MaxAge,
-		Compress:   fileConfig.Compress,
+// newEncoder 按编码创建Encoder，未配置时为json
+func newEncoder(encoding string, encoderConfig zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	switch encoding {
+	case "", "json":
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	case "console":
+		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	default:
+		return nil, fmt.Errorf("unknown encoding: %s", encoding)
+	}
+}
+
+// sampled 按sampling配置对每个输出单独采样，每秒内同一级别、同一消息超过initial条后每thereafter条记录一条
+func sampled(core zapcore.Core, sampling *zap.SamplingConfig) zapcore.Core {
+	if sampling == nil {
+		return core
+	}
+	return zapcore.NewSamplerWithOptions(core, time.Second, sampling.Initial, sampling.Thereafter)
+}
+
+// buildOptions 与zap.Config.Build一致的选项，initialFields附加到所有日志
+func buildOptions(config zap.Config, errorOutputs zapcore.WriteSyncer) []zap.Option {
+	opts := []zap.Option{zap.ErrorOutput(errorOutputs)}
+	if config.Development {
+		opts = append(opts, zap.Development())
+	}
+	if !config.DisableCaller {
+		opts = append(opts, zap.AddCaller())
+	}
+	stackLevel := zap.ErrorLevel
+	if config.Development {
+		stackLevel = zap.WarnLevel
+	}
+	if !config.DisableStacktrace {
+		opts = append(opts, zap.AddStacktrace(stackLevel))
+	}
+	if len(config.InitialFields) > 0 {
+		keys := make([]string, 0, len(config.InitialFields))
+		for key := range config.InitialFields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]zap.Field, 0, len(keys))
+		for _, key := range keys {
+			fields = append(fields, zap.Any(key, config.InitialFields[key]))
+		}
+		opts = append(opts, zap.Fields(fields...))
 	}
-	return lumberjackLogger
+	return opts
 }
 
 func GetLogger() *zap.SugaredLogger {
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Named 创建命名日志，输出时携带 logger 字段，级别可在日志配置文件的 levels 中按名称配置，
+// 未配置时按"."逐级向上继承，如：Named("payment.refund") 继承 payment 的级别。需在日志组件初始化后调用
+func Named(name string) *zap.SugaredLogger {
+	return baseLogger.Named(name).Sugar()
+}
+
+// levelFilterCore 按日志名称的生效级别过滤，被包装的core只负责按级别分发到不同的输出
+type levelFilterCore struct {
+	zapcore.Core
+}
+
+func newLevelFilterCore(core zapcore.Core) zapcore.Core {
+	return &levelFilterCore{Core: core}
+}
+
+// Enabled 任一日志开启该级别时返回true，具体过滤在Check中按名称进行
+func (c *levelFilterCore) Enabled(level zapcore.Level) bool {
+	return level >= minLevel()
+}
+
+func (c *levelFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelFilterCore{Core: c.Core.With(fields)}
+}
+
+func (c *levelFilterCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < NamedLevel(entry.LoggerName) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
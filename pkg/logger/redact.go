@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactCore 名称匹配 config.sensitive_patterns 的字段（如 password、token）输出为 ******，
+// With附加的字段与记录时的字段都会脱敏，嵌套对象中的字段不脱敏
+type redactCore struct {
+	zapcore.Core
+	sensitive func(name string) bool
+}
+
+// newRedactCore 包装输出core，创建时读取脱敏规则，日志重新加载时生效
+func newRedactCore(core zapcore.Core) zapcore.Core {
+	return &redactCore{Core: core, sensitive: config.SensitiveKeys()}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.redact(fields)), sensitive: c.sensitive}
+}
+
+func (c *redactCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, c.redact(fields))
+}
+
+// redact 返回脱敏后的字段，没有敏感字段时返回原切片
+func (c *redactCore) redact(fields []zapcore.Field) []zapcore.Field {
+	var redacted []zapcore.Field
+	for i, f := range fields {
+		if !c.sensitive(f.Key) {
+			continue
+		}
+		if redacted == nil {
+			redacted = append(make([]zapcore.Field, 0, len(fields)), fields...)
+		}
+		redacted[i] = zap.String(f.Key, config.RedactedValue)
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+)
+
+func TestRedactCoreMasksSensitiveFields(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(newRedactCore(core)).With(zap.String("access_token", "t0k3n"), zap.String("service", "pay"))
+	log.Info("login", zap.String("password", "hunter2"), zap.String("user", "alice"), zap.Int("retries", 1))
+	log.Sugar().Infow("connect", "db_password", "hunter2", "host", "localhost")
+
+	want := []map[string]interface{}{
+		{"access_token": config.RedactedValue, "service": "pay", "password": config.RedactedValue, "user": "alice", "retries": int64(1)},
+		{"access_token": config.RedactedValue, "service": "pay", "db_password": config.RedactedValue, "host": "localhost"},
+	}
+	entries := logs.AllUntimed()
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		got := entry.ContextMap()
+		if len(got) != len(want[i]) {
+			t.Fatalf("entry %d fields = %v, want %v", i, got, want[i])
+		}
+		for key, value := range want[i] {
+			if got[key] != value {
+				t.Errorf("entry %d %s = %v, want %v", i, key, got[key], value)
+			}
+		}
+	}
+}
+
+func TestRedactCoreKeepsFieldsWithoutSensitiveNames(t *testing.T) {
+	c := newRedactCore(zapcore.NewNopCore()).(*redactCore)
+	fields := []zapcore.Field{zap.String("user", "alice"), zap.Int("count", 2)}
+	if got := c.redact(fields); &got[0] != &fields[0] {
+		t.Fatal("fields without sensitive names must not be copied")
+	}
+	masked := []zapcore.Field{zap.String("secret", "s"), zap.String("user", "alice")}
+	got := c.redact(masked)
+	if got[0].String != config.RedactedValue || masked[0].String != "s" {
+		t.Fatalf("redact must mask a copy, got %v, original %v", got[0].String, masked[0].String)
+	}
+}
@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"fmt"
+	"github.com/natefinch/lumberjack"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"path/filepath"
+	"strings"
+)
+
+// nameField 按日志名称过滤时使用的字段，与encoderConfig.nameKey一致，匹配名称及其下级，如：access 匹配 access.http
+const nameField = "logger"
+
+type (
+	// SinkConfig 日志输出规则，按级别范围与字段过滤输出到文件，未配置的滚动参数与rolling一致，如：
+	//
+	//	sinks:
+	//	  - path: "access.log"
+	//	    match: {logger: access}
+	//	  - path: "debug.log"
+	//	    maxLevel: debug
+	SinkConfig struct {
+		// Path 文件路径，相对路径位于rolling.logFilePath下；stdout、stderr输出到标准输出、标准错误
+		Path string `json:"path" yaml:"path"`
+		// MinLevel、MaxLevel 输出的级别范围，默认为debug、fatal，同时受日志级别限制
+		MinLevel string `json:"minLevel" yaml:"minLevel"`
+		MaxLevel string `json:"maxLevel" yaml:"maxLevel"`
+		// Match 只输出所有字段都匹配的日志，Exclude 不输出任一字段匹配的日志，值按字符串比较
+		Match   map[string]string `json:"match" yaml:"match"`
+		Exclude map[string]string `json:"exclude" yaml:"exclude"`
+		// Encoding 编码：json、console，默认与rolling.encoding一致
+		Encoding   string `json:"encoding" yaml:"encoding"`
+		MaxSize    int    `json:"maxSize" yaml:"maxSize"`
+		MaxBackups int    `json:"maxBackups" yaml:"maxBackups"`
+		MaxAge     int    `json:"maxAge" yaml:"maxAge"`
+		Compress   *bool  `json:"compress" yaml:"compress"`
+	}
+
+	// filterCore 按字段过滤日志，With附加的字段与记录时的字段都参与匹配
+	filterCore struct {
+		zapcore.Core
+		match, exclude map[string]string
+		fields         []zapcore.Field
+	}
+)
+
+// sinksOf 配置的输出规则，未配置时按rolling输出info、warn、error（及以上）三个文件
+func sinksOf(wrapper ConfigWrapper) []SinkConfig {
+	if len(wrapper.Sinks) > 0 {
+		return wrapper.Sinks
+	}
+	rolling := wrapper.Rolling
+	sinks := make([]SinkConfig, 0, 3)
+	for _, sink := range []SinkConfig{
+		{Path: rolling.InfoFileName, MinLevel: "info", MaxLevel: "info"},
+		{Path: rolling.WarnFileName, MinLevel: "warn", MaxLevel: "warn"},
+		{Path: rolling.ErrorFileName, MinLevel: "error", MaxLevel: "fatal"},
+	} {
+		if sink.Path != "" {
+			sinks = append(sinks, sink)
+		}
+	}
+	return sinks
+}
+
+// newSinkCore 创建输出规则对应的core，返回关闭输出的函数
+func newSinkCore(sink SinkConfig, wrapper ConfigWrapper) (zapcore.Core, func(), error) {
+	rolling := wrapper.Rolling
+	if sink.Path == "" {
+		return nil, nil, fmt.Errorf("logger sink: path is required")
+	}
+	minLevel, err := parseSinkLevel(sink.MinLevel, zapcore.DebugLevel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("logger sink %s: minLevel: %w", sink.Path, err)
+	}
+	maxLevel, err := parseSinkLevel(sink.MaxLevel, zapcore.FatalLevel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("logger sink %s: maxLevel: %w", sink.Path, err)
+	}
+
+	encoding := sink.Encoding
+	if encoding == "" {
+		encoding = rolling.Encoding
+	}
+	if encoding == "" {
+		encoding = wrapper.Default.Encoding
+	}
+	encoder, err := newEncoder(encoding, wrapper.Default.EncoderConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("logger sink %s: %w", sink.Path, err)
+	}
+
+	var (
+		writer zapcore.WriteSyncer
+		closer func()
+	)
+	switch sink.Path {
+	case "stdout", "stderr":
+		if writer, closer, err = zap.Open(sink.Path); err != nil {
+			return nil, nil, fmt.Errorf("logger sink %s: %w", sink.Path, err)
+		}
+	default:
+		fileWriter := newRollingWriter(sink, rolling)
+		writer = zapcore.AddSync(fileWriter)
+		closer = func() {
+			fileWriter.Close()
+		}
+	}
+
+	levelRange := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+		return level >= minLevel && level <= maxLevel
+	})
+	core := newRedactCore(zapcore.NewCore(encoder, writer, levelRange))
+	if len(sink.Match) > 0 || len(sink.Exclude) > 0 {
+		core = &filterCore{Core: core, match: sink.Match, exclude: sink.Exclude}
+	}
+	return sampled(core, wrapper.Default.Sampling), closer, nil
+}
+
+// newRollingWriter 滚动日志文件，未配置的参数与rolling一致
+func newRollingWriter(sink SinkConfig, rolling RollingFileConfig) *lumberjack.Logger {
+	filename := sink.Path
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(rolling.LogFilePath, filename)
+	}
+	writer := &lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    rolling.MaxSize,
+		MaxBackups: rolling.MaxBackups,
+		MaxAge:     rolling.MaxAge,
+		Compress:   rolling.Compress,
+	}
+	if sink.MaxSize > 0 {
+		writer.MaxSize = sink.MaxSize
+	}
+	if sink.MaxBackups > 0 {
+		writer.MaxBackups = sink.MaxBackups
+	}
+	if sink.MaxAge > 0 {
+		writer.MaxAge = sink.MaxAge
+	}
+	if sink.Compress != nil {
+		writer.Compress = *sink.Compress
+	}
+	return writer
+}
+
+func parseSinkLevel(level string, def zapcore.Level) (zapcore.Level, error) {
+	if level == "" {
+		return def, nil
+	}
+	return zapcore.ParseLevel(level)
+}
+
+func (c *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	accumulated := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	accumulated = append(append(accumulated, c.fields...), fields...)
+	return &filterCore{Core: c.Core.With(fields), match: c.match, exclude: c.exclude, fields: accumulated}
+}
+
+// Check 记录时的字段只在Write中可见，级别匹配时先加入，在Write中按字段过滤
+func (c *filterCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c *filterCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	values := c.values(entry, fields)
+	for key, want := range c.match {
+		if !matchValue(key, values[key], want) {
+			return nil
+		}
+	}
+	for key, unwanted := range c.exclude {
+		if matchValue(key, values[key], unwanted) {
+			return nil
+		}
+	}
+	return c.Core.Write(entry, fields)
+}
+
+// values 参与过滤的字段值，记录时的字段覆盖With附加的字段
+func (c *filterCore) values(entry zapcore.Entry, fields []zapcore.Field) map[string]string {
+	values := map[string]string{nameField: entry.LoggerName}
+	for _, list := range [][]zapcore.Field{c.fields, fields} {
+		for _, f := range list {
+			if _, ok := c.match[f.Key]; !ok {
+				if _, ok := c.exclude[f.Key]; !ok {
+					continue
+				}
+			}
+			enc := zapcore.NewMapObjectEncoder()
+			f.AddTo(enc)
+			values[f.Key] = fmt.Sprint(enc.Fields[f.Key])
+		}
+	}
+	return values
+}
+
+func matchValue(key, value, want string) bool {
+	if key == nameField {
+		return value == want || strings.HasPrefix(value, want+".")
+	}
+	return value == want
+}
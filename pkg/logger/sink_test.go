@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilterCore(t *testing.T) {
+	tests := []struct {
+		name    string
+		match   map[string]string
+		exclude map[string]string
+		logger  string
+		with    []zapcore.Field
+		fields  []zapcore.Field
+		written bool
+	}{
+		{"match field", map[string]string{"tenant": "a"}, nil, "", nil, []zapcore.Field{zap.String("tenant", "a")}, true},
+		{"match field mismatch", map[string]string{"tenant": "a"}, nil, "", nil, []zapcore.Field{zap.String("tenant", "b")}, false},
+		{"match missing field", map[string]string{"tenant": "a"}, nil, "", nil, nil, false},
+		{"match non-string field", map[string]string{"status": "500"}, nil, "", nil, []zapcore.Field{zap.Int("status", 500)}, true},
+		{"match field added by With", map[string]string{"tenant": "a"}, nil, "", []zapcore.Field{zap.String("tenant", "a")}, nil, true},
+		{"entry field overrides With", map[string]string{"tenant": "a"}, nil, "", []zapcore.Field{zap.String("tenant", "a")}, []zapcore.Field{zap.String("tenant", "b")}, false},
+		{"match all fields", map[string]string{"tenant": "a", "region": "eu"}, nil, "", nil, []zapcore.Field{zap.String("tenant", "a")}, false},
+		{"exclude field", nil, map[string]string{"path": "/health"}, "", nil, []zapcore.Field{zap.String("path", "/health")}, false},
+		{"exclude other value", nil, map[string]string{"path": "/health"}, "", nil, []zapcore.Field{zap.String("path", "/api")}, true},
+		{"logger name", map[string]string{"logger": "access"}, nil, "access", nil, nil, true},
+		{"child logger name", map[string]string{"logger": "access"}, nil, "access.http", nil, nil, true},
+		{"logger name prefix is not a child", map[string]string{"logger": "access"}, nil, "accesslog", nil, nil, false},
+		{"parent logger name", map[string]string{"logger": "access.http"}, nil, "access", nil, nil, false},
+		{"exclude child logger", map[string]string{"logger": "access"}, map[string]string{"logger": "access.health"}, "access.health.probe", nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			log := zap.New(&filterCore{Core: core, match: tt.match, exclude: tt.exclude}).Named(tt.logger).With(tt.with...)
+			log.Info("message", tt.fields...)
+			if got := logs.Len() == 1; got != tt.written {
+				t.Fatalf("written = %v, want %v", got, tt.written)
+			}
+		})
+	}
+}
+
+func TestSinksWriteToOwnFiles(t *testing.T) {
+	dir := t.TempDir()
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.NameKey = nameField
+	wrapper := ConfigWrapper{
+		Default: zap.Config{Encoding: "json", EncoderConfig: encoderConfig},
+		Rolling: RollingFileConfig{LogFilePath: dir},
+	}
+	sinks := []SinkConfig{
+		{Path: "info.log", MinLevel: "info", MaxLevel: "info"},
+		{Path: "warn-and-above.log", MinLevel: "warn"},
+		{Path: "debug.log", MaxLevel: "debug"},
+		{Path: "access.log", Match: map[string]string{"logger": "access"}, Exclude: map[string]string{"path": "/health"}},
+	}
+	cores := make([]zapcore.Core, 0, len(sinks))
+	for _, sink := range sinks {
+		core, closer, err := newSinkCore(sink, wrapper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closer()
+		cores = append(cores, core)
+	}
+	log := zap.New(zapcore.NewTee(cores...))
+
+	log.Debug("debug")
+	log.Info("info")
+	log.Warn("warn")
+	log.Error("error")
+	access := log.Named("access.http")
+	access.Info("request", zap.String("path", "/api"))
+	access.Info("probe", zap.String("path", "/health"))
+	if err := log.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"info.log":           {"info", "request", "probe"},
+		"warn-and-above.log": {"warn", "error"},
+		"debug.log":          {"debug"},
+		"access.log":         {"request"},
+	}
+	for file, messages := range want {
+		if got := readMessages(t, filepath.Join(dir, file)); !equalMessages(got, messages) {
+			t.Errorf("%s = %v, want %v", file, got, messages)
+		}
+	}
+}
+
+// readMessages 读取json日志文件中每一行的msg
+func readMessages(t *testing.T, file string) []string {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var messages []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry struct {
+			Msg string `json:"msg"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: %q: %v", file, scanner.Text(), err)
+		}
+		messages = append(messages, entry.Msg)
+	}
+	return messages
+}
+
+func equalMessages(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
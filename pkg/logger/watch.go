@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// reloadDebounce 文件变更后等待的时间，合并编辑器、ConfigMap更新时产生的多次事件
+const reloadDebounce = 500 * time.Millisecond
+
+var (
+	watcherMu     sync.Mutex
+	watcher       *fsnotify.Watcher
+	subscribeOnce sync.Once
+)
+
+// Watch 监听日志配置文件所在的目录及配置中的 logger 命名空间，变更时更新日志级别
+func Watch(filename string) error {
+	watcherMu.Lock()
+	defer watcherMu.Unlock()
+	if watcher != nil {
+		return nil
+	}
+	// logger 命名空间随配置组件热加载，订阅无法取消，只订阅一次
+	subscribeOnce.Do(func() {
+		config.OnChange(constant.LoggerConfig, func(old, new *config.Configuration) {
+			reloadLevel(filename)
+		})
+	})
+	// 只使用 logger 命名空间时不需要监听日志配置文件
+	if _, err := os.Stat(filename); err != nil && fromConfig() {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("logger watch: %w", err)
+	}
+	dir := filepath.Dir(filename)
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return fmt.Errorf("logger watch %s: %w", dir, err)
+	}
+	watcher = w
+	go watchLoop(w, filename)
+	return nil
+}
+
+// StopWatch 停止监听日志配置文件
+func StopWatch() {
+	watcherMu.Lock()
+	defer watcherMu.Unlock()
+	if watcher != nil {
+		watcher.Close()
+		watcher = nil
+	}
+}
+
+func watchLoop(w *fsnotify.Watcher, filename string) {
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			// 同目录下的其他文件变更不处理；ConfigMap切换..data符号链接时文件名不同，同样需要重新加载
+			if event.Op == fsnotify.Chmod || (filepath.Base(event.Name) != filepath.Base(filename) && filepath.Base(event.Name) != "..data") {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(reloadDebounce, func() {
+				reloadLevel(filename)
+			})
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			Warnf("logger watch error: %v", err)
+		}
+	}
+}
+
+// reloadLevel 重新读取日志配置中根日志与命名日志的级别，读取失败时保留当前级别
+func reloadLevel(filename string) {
+	wrapper, err := loadConfig(filename)
+	if err != nil {
+		Errorf("reload logger config rejected, keep current level: %v", err)
+		return
+	}
+	named, err := parseNamedLevels(wrapper.Levels)
+	if err != nil {
+		Errorf("reload logger config rejected, keep current level: %v", err)
+		return
+	}
+	level := levelOf(wrapper.Default)
+	if !configuredLevelsChanged(level, named) {
+		return
+	}
+	setConfiguredLevels(level, named)
+	Infow("log level changed by logger config", "level", level.String(), "loggers", wrapper.Levels)
+}
@@ -0,0 +1,188 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OverlapPolicy 上一次执行尚未结束时，新的触发如何处理
+type OverlapPolicy int
+
+const (
+	// OverlapSkip 跳过本次触发（默认）
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue 排队，上一次执行结束后立即执行
+	OverlapQueue
+	// OverlapAllow 允许并发执行
+	OverlapAllow
+)
+
+func (p OverlapPolicy) String() string {
+	switch p {
+	case OverlapQueue:
+		return "queue"
+	case OverlapAllow:
+		return "allow"
+	default:
+		return "skip"
+	}
+}
+
+type (
+	// JobFunc 任务函数，ctx中携带本次执行的tid，停机超时后ctx被取消
+	JobFunc func(ctx context.Context) error
+
+	// JobOption 任务选项
+	JobOption func(job *Job)
+
+	// Job 定时任务
+	Job struct {
+		name     string
+		spec     string
+		schedule Schedule
+		fn       JobFunc
+		overlap  OverlapPolicy
+		jitter   time.Duration
+
+		mu           sync.Mutex
+		running      int
+		queued       int
+		runs         int64
+		failures     int64
+		skipped      int64
+		lastTid      string
+		lastStart    time.Time
+		lastDuration time.Duration
+		lastErr      error
+		next         time.Time
+	}
+
+	// JobStatus 任务运行状态
+	JobStatus struct {
+		Name         string     `json:"name"`
+		Spec         string     `json:"spec"`
+		Overlap      string     `json:"overlap"`
+		Jitter       string     `json:"jitter,omitempty"`
+		Running      int        `json:"running"`
+		Queued       int        `json:"queued"`
+		Runs         int64      `json:"runs"`
+		Failures     int64      `json:"failures"`
+		Skipped      int64      `json:"skipped"`
+		LastTid      string     `json:"lastTid,omitempty"`
+		LastStart    *time.Time `json:"lastStart,omitempty"`
+		LastDuration string     `json:"lastDuration,omitempty"`
+		LastError    string     `json:"lastError,omitempty"`
+		Next         *time.Time `json:"next,omitempty"`
+	}
+)
+
+// WithOverlap 设置重叠执行策略
+func WithOverlap(policy OverlapPolicy) JobOption {
+	return func(job *Job) {
+		job.overlap = policy
+	}
+}
+
+// WithJitter 每次触发随机延迟 [0, jitter)，避免多实例同时执行
+func WithJitter(jitter time.Duration) JobOption {
+	return func(job *Job) {
+		job.jitter = jitter
+	}
+}
+
+// Name 任务名称
+func (j *Job) Name() string {
+	return j.name
+}
+
+// Status 任务运行状态快照
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := JobStatus{
+		Name:     j.name,
+		Spec:     j.spec,
+		Overlap:  j.overlap.String(),
+		Running:  j.running,
+		Queued:   j.queued,
+		Runs:     j.runs,
+		Failures: j.failures,
+		Skipped:  j.skipped,
+		LastTid:  j.lastTid,
+	}
+	if j.jitter > 0 {
+		status.Jitter = j.jitter.String()
+	}
+	if !j.lastStart.IsZero() {
+		lastStart := j.lastStart
+		status.LastStart = &lastStart
+		status.LastDuration = j.lastDuration.String()
+	}
+	if j.lastErr != nil {
+		status.LastError = j.lastErr.Error()
+	}
+	if !j.next.IsZero() {
+		next := j.next
+		status.Next = &next
+	}
+	return status
+}
+
+// acquire 按重叠策略判断本次触发是否需要启动新的执行
+func (j *Job) acquire() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.running > 0 {
+		switch j.overlap {
+		case OverlapSkip:
+			j.skipped++
+			return false
+		case OverlapQueue:
+			j.queued++
+			return false
+		}
+	}
+	j.running++
+	return true
+}
+
+// release 执行结束，存在排队的触发时返回true继续执行；调度器停止时丢弃排队的触发，计入跳过次数
+func (j *Job) release(stopping bool) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if stopping {
+		j.skipped += int64(j.queued)
+		j.queued = 0
+	}
+	if j.queued > 0 {
+		j.queued--
+		return true
+	}
+	j.running--
+	return false
+}
+
+func (j *Job) setNext(next time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.next = next
+}
+
+func (j *Job) begin(tid string, start time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.lastTid = tid
+	j.lastStart = start
+}
+
+func (j *Job) finish(duration time.Duration, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.runs++
+	j.lastDuration = duration
+	j.lastErr = err
+	if err != nil {
+		j.failures++
+	}
+}
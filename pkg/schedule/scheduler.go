@@ -0,0 +1,241 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/chnyangzhen/kago-fly/pkg/helper"
+	"github.com/chnyangzhen/kago-fly/pkg/logger"
+	"github.com/chnyangzhen/kago-fly/pkg/server"
+	"github.com/chnyangzhen/kago-fly/pkg/tidctx"
+	"github.com/labstack/echo/v4"
+	"math/rand"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var scheduler = &Scheduler{names: make(map[string]*Job)}
+
+func init() {
+	server.AddLifecycle(scheduler)
+	server.Listen(constant.AdminListener).RegisterRoute(http.MethodGet, "/jobs", jobStatuses)
+}
+
+// Scheduler 定时任务调度器，以生命周期组件的方式运行：OnAfter启动调度，OnDestroy停止调度并等待执行中的任务
+type Scheduler struct {
+	mu    sync.Mutex
+	jobs  []*Job
+	names map[string]*Job
+
+	started    bool
+	loopCtx    context.Context
+	stopLoops  context.CancelFunc
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+	loops      sync.WaitGroup
+	runs       sync.WaitGroup
+}
+
+// RegisterJob 注册定时任务，spec支持cron表达式与固定间隔（见Parse），
+// 如：schedule.RegisterJob("@every 1m", "cache-warmup", warmup, schedule.WithJitter(5*time.Second))。
+// 规则不合法或任务名称重复时panic
+func RegisterJob(spec, name string, fn JobFunc, opts ...JobOption) *Job {
+	job, err := scheduler.Add(spec, name, fn, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return job
+}
+
+// Jobs 返回所有任务的运行状态
+func Jobs() []JobStatus {
+	return scheduler.Statuses()
+}
+
+// Add 添加任务，调度器已启动时立即开始调度
+func (s *Scheduler) Add(spec, name string, fn JobFunc, opts ...JobOption) (*Job, error) {
+	sched, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		name:     name,
+		spec:     spec,
+		schedule: sched,
+		fn:       fn,
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.names[name]; ok {
+		return nil, fmt.Errorf("schedule: job %s already exists", name)
+	}
+	s.names[name] = job
+	s.jobs = append(s.jobs, job)
+	if s.started {
+		s.loops.Add(1)
+		go s.loop(job)
+	}
+	return job, nil
+}
+
+// Statuses 返回所有任务的运行状态，按注册顺序排列
+func (s *Scheduler) Statuses() []JobStatus {
+	s.mu.Lock()
+	jobs := make([]*Job, len(s.jobs))
+	copy(jobs, s.jobs)
+	s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		statuses = append(statuses, job.Status())
+	}
+	return statuses
+}
+
+func (s *Scheduler) Title() string {
+	return "schedule"
+}
+
+// OnAfter 监听器启动后开始调度
+func (s *Scheduler) OnAfter() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil
+	}
+	s.loopCtx, s.stopLoops = context.WithCancel(context.Background())
+	s.runCtx, s.cancelRuns = context.WithCancel(context.Background())
+	s.started = true
+	for _, job := range s.jobs {
+		s.loops.Add(1)
+		go s.loop(job)
+	}
+	logger.Infof("schedule started with %d jobs", len(s.jobs))
+	return nil
+}
+
+// OnDestroy 停止触发新的执行，等待执行中的任务结束；超过ctx截止时间后取消执行中的任务
+func (s *Scheduler) OnDestroy(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	s.started = false
+	s.stopLoops()
+	s.mu.Unlock()
+	s.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancelRuns()
+		return nil
+	case <-ctx.Done():
+		s.cancelRuns()
+		for _, status := range s.Statuses() {
+			if status.Running > 0 {
+				logger.TraceId(status.LastTid).Warnw("job still running at shutdown deadline, cancelled", "job", status.Name)
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// loop 按任务规则循环触发，直到调度器停止
+func (s *Scheduler) loop(job *Job) {
+	defer s.loops.Done()
+	for {
+		next := job.schedule.Next(time.Now())
+		if next.IsZero() {
+			logger.Warnw("job has no next run time, stop scheduling", "job", job.name, "spec", job.spec)
+			return
+		}
+		if job.jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(job.jitter))))
+		}
+		job.setNext(next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.loopCtx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.trigger(job)
+		}
+	}
+}
+
+// trigger 按重叠策略启动任务执行，排队的触发在当前执行结束后依次执行
+func (s *Scheduler) trigger(job *Job) {
+	if !job.acquire() {
+		if job.overlap == OverlapSkip {
+			logger.Warnw("job skipped, previous run still in progress", "job", job.name)
+		}
+		return
+	}
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		for {
+			s.execute(job)
+			if !job.release(s.loopCtx.Err() != nil) {
+				return
+			}
+		}
+	}()
+}
+
+// execute 执行一次任务，每次执行生成新的tid，panic转换为错误
+func (s *Scheduler) execute(job *Job) {
+	tid := helper.Uuid()
+	ctx, cancel := context.WithCancel(tidctx.InitTidCtx(tid))
+	defer cancel()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.runCtx.Done():
+			cancel()
+		case <-done:
+		}
+	}()
+
+	log := logger.Trace(ctx)
+	start := time.Now()
+	job.begin(tid, start)
+	log.Infow("job started", "job", job.name)
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Errorw("job panic", "job", job.name, "panic", r, "stack", string(debug.Stack()))
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return job.fn(ctx)
+	}()
+
+	elapsed := time.Since(start)
+	job.finish(elapsed, err)
+	if err != nil {
+		log.Errorw("job failed", "job", job.name, "error", err, "elapsed", elapsed.String())
+		return
+	}
+	log.Infow("job completed", "job", job.name, "elapsed", elapsed.String())
+}
+
+func jobStatuses(c echo.Context) error {
+	return server.WriteSuccess(c, Jobs())
+}
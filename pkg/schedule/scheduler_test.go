@@ -0,0 +1,183 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/logger"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testLogger = `
+default:
+  level: "warn"
+  encoding: "console"
+  outputPaths: ["stderr"]
+rolling:
+  logFilePath: "%s"
+  errorFileName: "error.log"
+`
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "kago-schedule-test")
+	if err != nil {
+		panic(err)
+	}
+	code := func() int {
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "logger.yml")
+		if err := os.WriteFile(file, []byte(fmt.Sprintf(testLogger, dir)), 0o644); err != nil {
+			panic(err)
+		}
+		if err := logger.InitLogger(file); err != nil {
+			panic(err)
+		}
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+// blockingJob 每次执行阻塞到 unblock 被关闭，记录执行次数与最大并发数
+type blockingJob struct {
+	unblock     chan struct{}
+	runs        int32
+	running     int32
+	maxParallel int32
+}
+
+func newBlockingJob() *blockingJob {
+	return &blockingJob{unblock: make(chan struct{})}
+}
+
+func (b *blockingJob) run(ctx context.Context) error {
+	atomic.AddInt32(&b.runs, 1)
+	n := atomic.AddInt32(&b.running, 1)
+	defer atomic.AddInt32(&b.running, -1)
+	for {
+		max := atomic.LoadInt32(&b.maxParallel)
+		if n <= max || atomic.CompareAndSwapInt32(&b.maxParallel, max, n) {
+			break
+		}
+	}
+	<-b.unblock
+	return nil
+}
+
+// newStartedScheduler 创建已启动但不运行调度循环的调度器，通过 trigger 手动触发
+func newStartedScheduler(t *testing.T) *Scheduler {
+	t.Helper()
+	s := &Scheduler{names: make(map[string]*Job)}
+	s.loopCtx, s.stopLoops = context.WithCancel(context.Background())
+	s.runCtx, s.cancelRuns = context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		s.stopLoops()
+		s.cancelRuns()
+	})
+	return s
+}
+
+func newJob(t *testing.T, s *Scheduler, fn JobFunc, policy OverlapPolicy) *Job {
+	t.Helper()
+	job, err := s.Add("@every 1h", t.Name(), fn, WithOverlap(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOverlapPolicies(t *testing.T) {
+	tests := []struct {
+		policy      OverlapPolicy
+		wantRuns    int32
+		wantSkipped int64
+		wantQueued  int
+		wantMax     int32
+	}{
+		{OverlapSkip, 1, 2, 0, 1},
+		{OverlapQueue, 3, 0, 2, 1},
+		{OverlapAllow, 3, 0, 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			s := newStartedScheduler(t)
+			b := newBlockingJob()
+			job := newJob(t, s, b.run, tt.policy)
+
+			for i := 0; i < 3; i++ {
+				s.trigger(job)
+			}
+			waitFor(t, "first run", func() bool { return atomic.LoadInt32(&b.runs) > 0 })
+			status := job.Status()
+			if status.Skipped != tt.wantSkipped || status.Queued != tt.wantQueued {
+				t.Fatalf("skipped = %d, queued = %d, want %d, %d", status.Skipped, status.Queued, tt.wantSkipped, tt.wantQueued)
+			}
+
+			close(b.unblock)
+			s.runs.Wait()
+			status = job.Status()
+			if got := atomic.LoadInt32(&b.runs); got != tt.wantRuns || status.Runs != int64(tt.wantRuns) {
+				t.Fatalf("runs = %d (status %d), want %d", got, status.Runs, tt.wantRuns)
+			}
+			if got := atomic.LoadInt32(&b.maxParallel); got != tt.wantMax {
+				t.Fatalf("max parallel runs = %d, want %d", got, tt.wantMax)
+			}
+			if status.Running != 0 || status.Queued != 0 {
+				t.Fatalf("running = %d, queued = %d after all runs finished", status.Running, status.Queued)
+			}
+		})
+	}
+}
+
+func TestStopDropsQueuedTriggers(t *testing.T) {
+	s := newStartedScheduler(t)
+	b := newBlockingJob()
+	job := newJob(t, s, b.run, OverlapQueue)
+
+	for i := 0; i < 3; i++ {
+		s.trigger(job)
+	}
+	waitFor(t, "first run", func() bool { return atomic.LoadInt32(&b.runs) > 0 })
+	s.stopLoops()
+	close(b.unblock)
+	s.runs.Wait()
+
+	status := job.Status()
+	if status.Runs != 1 {
+		t.Fatalf("runs = %d, queued triggers must not run after stop", status.Runs)
+	}
+	if status.Queued != 0 || status.Running != 0 {
+		t.Fatalf("running = %d, queued = %d, want both 0 after stop", status.Running, status.Queued)
+	}
+	if status.Skipped != 2 {
+		t.Fatalf("skipped = %d, want dropped triggers counted as skipped", status.Skipped)
+	}
+}
+
+func TestAddRejectsDuplicateAndInvalidJobs(t *testing.T) {
+	s := &Scheduler{names: make(map[string]*Job)}
+	noop := func(ctx context.Context) error { return nil }
+	if _, err := s.Add("@every 1m", "job", noop); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add("@every 1m", "job", noop); err == nil {
+		t.Fatal("duplicate job name must be rejected")
+	}
+	if _, err := s.Add("not a spec", "other", noop); err == nil {
+		t.Fatal("invalid spec must be rejected")
+	}
+}
@@ -0,0 +1,81 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	// 2024-01-01 为周一
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"step", "*/15 * * * *", date(2024, 1, 1, 10, 7), date(2024, 1, 1, 10, 15)},
+		{"step on exact match moves on", "*/15 * * * *", date(2024, 1, 1, 10, 15), date(2024, 1, 1, 10, 30)},
+		{"seconds are truncated", "* * * * *", date(2024, 1, 1, 10, 7).Add(30 * time.Second), date(2024, 1, 1, 10, 8)},
+		{"range with step", "10-20/5 * * * *", date(2024, 1, 1, 10, 16), date(2024, 1, 1, 10, 20)},
+		{"start with step", "5/20 * * * *", date(2024, 1, 1, 10, 6), date(2024, 1, 1, 10, 25)},
+		{"list", "0 8,20 * * *", date(2024, 1, 1, 9, 0), date(2024, 1, 1, 20, 0)},
+		{"weekday names", "0 3 * * mon-fri", date(2024, 1, 6, 0, 0), date(2024, 1, 8, 3, 0)},
+		{"month names", "0 0 * jan,jul *", date(2024, 2, 1, 0, 0), date(2024, 7, 1, 0, 0)},
+		{"dom range rolls over month", "30 9 1-7 * *", date(2024, 1, 8, 0, 0), date(2024, 2, 1, 9, 30)},
+		{"sunday as 7", "0 0 * * 7", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 0, 0)},
+		{"question mark", "0 0 1 * ?", date(2024, 1, 1, 0, 0), date(2024, 2, 1, 0, 0)},
+		{"dom or dow matches dow", "0 0 13 * fri", date(2024, 1, 1, 0, 0), date(2024, 1, 5, 0, 0)},
+		{"dom or dow matches dom", "0 0 13 * fri", date(2024, 1, 12, 0, 0), date(2024, 1, 13, 0, 0)},
+		{"dom with star dow", "0 0 13 * *", date(2024, 1, 1, 0, 0), date(2024, 1, 13, 0, 0)},
+		{"leap day", "0 0 29 2 *", date(2024, 3, 1, 0, 0), date(2028, 2, 29, 0, 0)},
+		{"daily", "@daily", date(2024, 1, 1, 10, 7), date(2024, 1, 2, 0, 0)},
+		{"weekly", "@weekly", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 0, 0)},
+		{"hourly", "@HOURLY", date(2024, 1, 1, 10, 7), date(2024, 1, 1, 11, 0)},
+		{"yearly", "@yearly", date(2024, 1, 1, 0, 0), date(2025, 1, 1, 0, 0)},
+		{"impossible february 30", "0 0 30 feb *", date(2024, 1, 1, 0, 0), time.Time{}},
+		{"impossible april 31", "0 0 31 4 *", date(2024, 1, 1, 0, 0), time.Time{}},
+		{"every", "@every 90s", date(2024, 1, 1, 10, 7), date(2024, 1, 1, 10, 8).Add(30 * time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.spec, err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Fatalf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a-5 * * * *",
+		"* * * foo *",
+		"@every",
+		"@every abc",
+		"@every -1s",
+		"@every 0s",
+		"@sometimes",
+	}
+	for _, spec := range tests {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", spec)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+func init() {
+	Listen(constant.AdminListener).RegisterRoute(http.MethodGet, "/config", effectiveConfig)
+}
+
+// effectiveConfig 输出生效的配置及每个Key的来源，敏感配置已脱敏，可通过 ?prefix=listeners 过滤
+func effectiveConfig(c echo.Context) error {
+	return WriteSuccess(c, config.Effective(c.QueryParam("prefix")))
+}
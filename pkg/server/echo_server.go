@@ -11,202 +11,454 @@ import (
 	"github.com/chnyangzhen/kago-fly/pkg/tidctx"
 	"github.com/chnyangzhen/kago-fly/pkg/validator"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
+	"go.uber.org/multierr"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 )
 
+const defaultShutdownTimeout = 10 * time.Second
+
 var (
 	s               *Server
+	dataValidator   = &validator.DataValidator{}
 	shutdownSignals = []os.Signal{os.Interrupt, os.Kill, syscall.SIGKILL, syscall.SIGSTOP,
 		syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGILL, syscall.SIGTRAP,
 		syscall.SIGABRT, syscall.SIGSYS, syscall.SIGTERM}
 )
 
 func init() {
-	s = &Server{echo.New(), sync.Map{}, &sync.WaitGroup{}}
+	s = &Server{
+		prepared: make(map[string]bool),
+		started:  make(chan struct{}),
+		quit:     make(chan os.Signal, 1),
+	}
 }
 
 // Server 服务器实例
 type Server struct {
-	*echo.Echo // Web服务实例的包装，如echo框架的包装
-	routes     sync.Map
-	waiting    *sync.WaitGroup
+	*echo.Echo // 默认监听器（listeners.web）的Web服务实例，兼容单监听器的用法
+	listeners  []*Listener
+
+	// mu 保护启动协程与停机流程共享的状态
+	mu       sync.Mutex
+	prepared map[string]bool // 已成功执行Prepare的组件
+	startErr error           // 第一个启动错误
+	stopping bool            // 停机开始后不再启动监听器
+	started  chan struct{}   // 启动流程（包括失败时的回滚）结束后关闭
+	quit     chan os.Signal
+
+	supervisors   []*supervisor
+	cancelWorkers context.CancelFunc
 }
 
+// prepare 执行Prepare生命周期，lifecycle.parallel开启时无依赖关系的组件并发执行。
+// 任一组件失败时回滚已准备成功的组件并返回所有失败组件的错误
 func (s *Server) prepare() error {
-	for _, prepare := range PrepareLifecycle() {
-		logger.Infof("Prepare lifecycle title: %s is ready.", prepare.Title())
-		if err := prepare.OnPrepare(); err != nil {
-			logger.Errorf("Prepare lifecycle title: %s error with %s", prepare.Title(), err.Error())
+	var err error
+	if config.GetWrapper(constant.LifecycleConfig).GetBool("parallel") {
+		err = s.prepareParallel(PrepareLifecycle())
+	} else {
+		err = s.prepareSerial(PrepareLifecycle())
+	}
+	if err != nil {
+		s.rollback()
+	}
+	return err
+}
+
+func (s *Server) prepareSerial(list []Preparer) error {
+	for _, prepare := range list {
+		if err := prepareOne(prepare); err != nil {
 			return err
 		}
-		logger.Infof("Prepare lifecycle title: %s completed.", prepare.Title())
+		s.setPrepared(prepare.Title(), true)
 	}
 	return nil
 }
 
-func NewServer() *Server {
-
-	webConfig := config.GetWrapper("listeners.web")
-	e := s.Echo
+// prepareParallel 使用有界协程池并发执行Prepare，组件在其依赖的组件全部准备成功后才会执行。
+// 出现失败后快速失败：不再调度新的组件，等待执行中的组件结束后汇总其中所有失败组件的错误，
+// 未执行的组件在错误中列出（不在PrepareTimings中），随后回滚已准备成功的组件
+func (s *Server) prepareParallel(list []Preparer) error {
+	workers := config.GetWrapper(constant.LifecycleConfig).GetInt("workers")
+	if workers <= 0 {
+		workers = defaultLifecycleWorkers
+	}
 
-	targetHeader := config.GetStringWithDefault("trace.id-key", constant.XRequestID)
-	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
-		Generator: func() string {
-			return helper.Uuid()
-		},
-		RequestIDHandler: func(ctx echo.Context, tid string) {
-			tidctx.InitWebTid(ctx, tid)
-		},
+	index := make(map[string]int, len(list))
+	for i, prepare := range list {
+		index[prepare.Title()] = i
+	}
+	pending := make(map[string]int, len(list))
+	dependents := make(map[string][]string, len(list))
+	for _, prepare := range list {
+		for _, dep := range dependencies[prepare.Title()] {
+			if _, ok := index[dep]; ok {
+				pending[prepare.Title()]++
+				dependents[dep] = append(dependents[dep], prepare.Title())
+			}
+		}
+	}
+	ready := make([]string, 0, len(list))
+	for _, prepare := range list {
+		if pending[prepare.Title()] == 0 {
+			ready = append(ready, prepare.Title())
+		}
+	}
 
-		TargetHeader: targetHeader,
-	}))
+	type result struct {
+		title string
+		err   error
+	}
+	results := make(chan result)
+	running := 0
+	scheduled := make(map[string]bool, len(list))
+	var errs error
+	for {
+		for errs == nil && running < workers && len(ready) > 0 {
+			prepare := list[index[ready[0]]]
+			ready = ready[1:]
+			running++
+			scheduled[prepare.Title()] = true
+			go func(prepare Preparer) {
+				results <- result{prepare.Title(), prepareOne(prepare)}
+			}(prepare)
+		}
+		if running == 0 {
+			break
+		}
 
-	e.HTTPErrorHandler = func(err error, c echo.Context) {
-		if helper.IsNil(err) {
-			return
+		r := <-results
+		running--
+		if r.err != nil {
+			errs = multierr.Append(errs, r.err)
+			continue
+		}
+		s.setPrepared(r.title, true)
+		for _, next := range dependents[r.title] {
+			pending[next]--
+			if pending[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+		sort.SliceStable(ready, func(i, j int) bool {
+			return index[ready[i]] < index[ready[j]]
+		})
+	}
+	if errs != nil {
+		skipped := make([]string, 0, len(list))
+		for _, prepare := range list {
+			if !scheduled[prepare.Title()] {
+				skipped = append(skipped, prepare.Title())
+			}
 		}
-		var r *response.Result
-		switch x := err.(type) {
-		case *response.InnerError:
-			r = response.NewInnerErrorFailedWith(x, tidctx.WebTid(c))
-		case *response.ParamError:
-			r = response.NewParamErrorWith(x, tidctx.WebTid(c))
-		default:
-			r = response.NewFailed("unknown error", tidctx.WebTid(c))
+		if len(skipped) > 0 {
+			errs = multierr.Append(errs, fmt.Errorf("lifecycle: prepare aborted after failure, not started: %s",
+				strings.Join(skipped, ", ")))
 		}
-		WriteJson(c, r)
 	}
+	return errs
+}
 
-	e.RouteNotFound("/*", func(c echo.Context) error {
-		notFound := response.NewFailed("api not found", tidctx.WebTid(c))
-		return WriteJsonWithCode(c, http.StatusNotFound, notFound)
-	})
+// rollback 逆序调用已准备成功的组件的OnDestroy
+func (s *Server) rollback() {
+	for _, destroy := range DestroyLifecycle() {
+		if !s.isPrepared(destroy.Title()) {
+			continue
+		}
+		logger.Infof("Rollback lifecycle title: %s is ready.", destroy.Title())
+		registry.transition(destroy.Title(), StateDestroying, nil)
+		if err := runHook(context.Background(), destroy.Title(), destroy.OnDestroy); err != nil {
+			registry.transition(destroy.Title(), StateFailed, err)
+			logger.Errorf("Rollback lifecycle title: %s error with %s", destroy.Title(), err.Error())
+		} else {
+			registry.transition(destroy.Title(), StateDestroyed, nil)
+			logger.Infof("Rollback lifecycle title: %s completed.", destroy.Title())
+		}
+		s.setPrepared(destroy.Title(), false)
+	}
+}
 
-	// 设置BodyLimit
-	if bodyLimit := webConfig.GetString("features.body_limit"); bodyLimit != "" {
-		logger.Infof("开启BodyLimit限制, body-limit: size= %s", bodyLimit)
-		e.Pre(middleware.BodyLimit(bodyLimit))
+func (s *Server) setPrepared(title string, prepared bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prepared {
+		s.prepared[title] = true
+	} else {
+		delete(s.prepared, title)
 	}
+}
 
-	// CORS（是否开启支持跨域访问特性）
-	if enabled := webConfig.GetBool("features.cors_enable"); enabled {
-		logger.Infof("开启跨域访问")
-		e.Pre(middleware.CORS())
+func (s *Server) isPrepared(title string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prepared[title]
+}
+
+// shouldDestroy 实现了Preparer的组件只有在准备成功（且未被回滚）时才执行Destroy
+func (s *Server) shouldDestroy(title string) bool {
+	if s.isPrepared(title) {
+		return true
+	}
+	for _, prepare := range PrepareLifecycle() {
+		if prepare.Title() == title {
+			return false
+		}
 	}
+	return true
+}
 
-	// CSRF（是否开启检查跨站请求伪造特性）
-	if enabled := webConfig.GetBool("features.csrf_enable"); enabled {
-		logger.Infof("开启CSRF")
-		e.Pre(middleware.CSRF())
+// NewServer 根据listeners下的每一项配置创建对应的监听器，监听器配置校验失败或未配置默认监听器（listeners.web）时返回错误
+func NewServer() (*Server, error) {
+	names := make([]string, 0)
+	for name := range config.GetWrapper(constant.ListenersConfig).ToStringMap() {
+		if isWorkerListener(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if !helper.ContainsString(names, constant.DefaultListener) {
+		return nil, fmt.Errorf("listener: %s.%s is not configured", constant.ListenersConfig, constant.DefaultListener)
 	}
 
-	e.HideBanner = true
+	s.listeners = make([]*Listener, 0, len(names))
+	for _, name := range names {
+		l, err := newListener(name)
+		if err != nil {
+			return nil, err
+		}
+		if name == constant.DefaultListener {
+			s.Echo = l.Echo
+		}
+		s.listeners = append(s.listeners, l)
+	}
 
-	// 捕获error，InnerError错误可以直接panic，不打印堆栈，其他错误需要打印堆栈
-	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
-		DisableStackAll:   true,
-		DisablePrintStack: true,
-		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
-			_, ok := err.(*response.ParamError)
-			if !ok {
-				tid := tidctx.WebTid(c)
-				logger.TraceId(tid).Errorw("recover error", "err", err, "stack", string(stack))
-			}
-			return err
-		},
-	}))
-	e.Validator = &validator.DataValidator{}
-	return s
+	routers.Range(func(key, value interface{}) bool {
+		if s.Listener(key.(string)) == nil {
+			logger.Warnf("listener: %s is not configured, routes registered on it are ignored", key)
+		}
+		return true
+	})
+	return s, nil
 }
 
-func (s *Server) StartGraceful() {
-	quit := make(chan os.Signal, 1)
+// Listener 根据名称获取已创建的监听器，不存在时返回nil
+func (s *Server) Listener(name string) *Listener {
+	for _, l := range s.listeners {
+		if l.name == name {
+			return l
+		}
+	}
+	return nil
+}
 
+// StartGraceful 启动服务并等待停机信号，启动失败时停机并返回启动错误
+func (s *Server) StartGraceful() error {
+	signal.Notify(s.quit, shutdownSignals...)
 	go func() {
+		defer close(s.started)
 		defer func() {
 			if r := recover(); r != nil {
 				logger.Errorw("系统运行异常,即将停止,请检查!", "error", r)
-				quit <- os.Interrupt
+				s.fail(fmt.Errorf("server start panic: %v", r))
 			}
 		}()
 		if err := s.start(); err != nil {
-			quit <- os.Interrupt
+			logger.Errorw("系统启动失败,即将停止,请检查!", "error", err)
+			s.fail(err)
 		}
 	}()
 
-	signal.Notify(quit, shutdownSignals...)
-	sig := <-quit
+	sig := <-s.quit
 	logger.Info("receive signal: ", sig)
 
-	s.stop(10 * time.Second)
+	s.stop()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.startErr
+}
+
+// fail 记录第一个启动错误并触发停机
+func (s *Server) fail(err error) {
+	s.mu.Lock()
+	if s.startErr == nil {
+		s.startErr = err
+	}
+	s.mu.Unlock()
+	select {
+	case s.quit <- os.Interrupt:
+	default:
+	}
+}
+
+func (s *Server) isStopping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopping
 }
 
 func (s *Server) start() error {
+	// 管理监听器先于Prepare启动，便于在启动阶段查看生命周期状态和健康检查
+	if admin := s.Listener(constant.AdminListener); admin != nil {
+		if err := s.startListener(admin); err != nil {
+			return err
+		}
+	}
+
 	// 生命周期准备阶段
-	s.prepare()
-
-	config := config.GetWrapper("listeners.web")
-
-	s.waiting.Add(1)
-	// 服务器启动
-	// 服务器初始化
-	// 自定义的前置过滤器
-	go func(e *echo.Echo, routes sync.Map, waiting *sync.WaitGroup) {
-		routes.Range(func(key, value interface{}) bool {
-			r := value.(*apiRoute)
-			e.Add(r.method, r.path, r.handler, r.middleware...)
-			return true
-		})
-		waiting.Done()
-		e.Start(config.GetString("address") + ":" + config.GetString("port"))
-	}(s.Echo, s.routes, s.waiting)
-	s.waiting.Wait()
-	return s.StartedAfter()
+	if err := s.prepare(); err != nil {
+		return err
+	}
+	// 准备阶段收到停机信号时不再启动监听器，已准备的组件由stop销毁
+	if s.isStopping() {
+		return nil
+	}
+
+	// 服务器启动，每个监听器挂载各自的路由后独立启动
+	for _, l := range s.listeners {
+		if l.name == constant.AdminListener {
+			continue
+		}
+		if err := s.startListener(l); err != nil {
+			return err
+		}
+	}
+	s.startWorkers()
+	if err := s.StartedAfter(); err != nil {
+		return err
+	}
+	// 与stop互斥，停机开始后不再标记为就绪
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.stopping {
+		setReady(true)
+	}
+	return nil
+}
+
+// startListener 挂载路由并绑定地址，绑定失败（如端口被占用、地址错误）时返回错误；
+// 绑定成功后在独立协程中处理请求，运行中出错时触发停机
+func (s *Server) startListener(l *Listener) error {
+	l.mountRoutes()
+	ln, err := net.Listen("tcp", l.address())
+	if err != nil {
+		return fmt.Errorf("listener %s: %w", l.name, err)
+	}
+	l.Listener = ln
+	logger.Infof("listener: %s listen on %s", l.name, ln.Addr())
+	go func() {
+		if err := l.Start(l.address()); err != nil && err != http.ErrServerClosed {
+			logger.Errorw("listener start error", "listener", l.name, "error", err)
+			s.fail(fmt.Errorf("listener %s: %w", l.name, err))
+		}
+	}()
+	return nil
 }
 
 func (s *Server) StartedAfter() error {
 	for _, startedAfter := range AfterLifecycle() {
 		logger.Infof("After lifecycle title: %s is ready.", startedAfter.Title())
-		if err := startedAfter.OnAfter(); err != nil {
+		if err := runHook(context.Background(), startedAfter.Title(), afterHook(startedAfter)); err != nil {
+			registry.transition(startedAfter.Title(), StateFailed, err)
 			logger.Errorf("After lifecycle title: %s error with %s", startedAfter.Title(), err.Error())
 			return err
 		}
+		registry.transition(startedAfter.Title(), StateReady, nil)
 		logger.Infof("After lifecycle title: %s completed.", startedAfter.Title())
 	}
 	return nil
 }
 
-func (s *Server) registerRouter(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
-	s.Echo.Add(method, path, handler, middleware...)
+// drain 停止接收新连接并等待处理中的请求完成，超时后打印仍未完成的请求。
+// 管理监听器不在此关闭，停机期间 /health/ready 返回未就绪
+func (s *Server) drain(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, l := range s.listeners {
+		if l.name == constant.AdminListener {
+			continue
+		}
+		wg.Add(1)
+		go func(l *Listener) {
+			defer wg.Done()
+			if err := l.Shutdown(ctx); err != nil {
+				logger.Errorw("server shutdown error", "listener", l.name, "error", err)
+				l.logInflight()
+			}
+		}(l)
+	}
+	wg.Wait()
 }
 
-func (s *Server) destroy(ctx context.Context) error {
-	err := s.Shutdown(ctx)
-	if err != nil {
-		logger.Errorw("server shutdown error", "error", err)
+// shutdownAdmin 最后关闭管理监听器，截止时间已过时直接关闭连接
+func (s *Server) shutdownAdmin(ctx context.Context) {
+	admin := s.Listener(constant.AdminListener)
+	if admin == nil {
+		return
+	}
+	if err := admin.Shutdown(ctx); err != nil {
+		admin.Close()
 	}
+}
 
+func (s *Server) destroy(ctx context.Context) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		logger.Infof("Destroy lifecycle with remaining budget: %s", time.Until(deadline))
+	}
 	for _, destroy := range DestroyLifecycle() {
-		fmt.Printf("destroy title: %s is ready.\n", destroy.Title())
-		if err := destroy.OnDestroy(ctx); err != nil {
-			fmt.Errorf("lifecycle destroy title: %s error: %s\n", destroy.Title(), err.Error())
+		if !s.shouldDestroy(destroy.Title()) {
+			continue
+		}
+		logger.Infof("Destroy lifecycle title: %s is ready.", destroy.Title())
+		registry.transition(destroy.Title(), StateDestroying, nil)
+		// 单个组件受 lifecycle.timeouts.{title} 限制，所有组件共享停机剩余的时间
+		if err := runHook(ctx, destroy.Title(), destroy.OnDestroy); err != nil {
+			registry.transition(destroy.Title(), StateFailed, err)
+			logger.Errorf("Destroy lifecycle title: %s error with %s", destroy.Title(), err.Error())
 		} else {
-			fmt.Printf("destroy title: %s completed.\n", destroy.Title())
+			registry.transition(destroy.Title(), StateDestroyed, nil)
+			logger.Infof("Destroy lifecycle title: %s completed.", destroy.Title())
 		}
 	}
 	return nil
 }
 
-func (s *Server) stop(timeout time.Duration) {
+// stop 停机流程：标记未就绪 -> 等待pre_stop_delay（负载均衡摘除流量）-> 停止接收连接并等待处理中的请求
+// -> 使用剩余的时间执行Destroy生命周期 -> 关闭管理监听器。shutdown_timeout为等待请求与Destroy的总时长。
+// 未曾就绪（启动失败或启动中）时不会有流量路由过来，跳过pre_stop_delay
+func (s *Server) stop() {
+	s.mu.Lock()
+	s.stopping = true
+	wasReady := IsReady()
+	setReady(false)
+	s.mu.Unlock()
+
+	webConfig := config.GetWrapper(config.MakeKey(constant.ListenersConfig, constant.DefaultListener))
+	timeout := webConfig.GetDuration("shutdown_timeout")
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	if delay := webConfig.GetDuration("pre_stop_delay"); delay > 0 && wasReady {
+		logger.Infof("pre-stop delay %s before shutdown", delay)
+		time.Sleep(delay)
+	}
+
+	// 等待启动流程（包括失败时的回滚）结束，避免与回滚并发执行Destroy；Prepare受超时控制，等待是有界的
+	<-s.started
+
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	s.drain(ctx)
+	s.stopWorkers(ctx)
 	s.destroy(ctx)
+	s.shutdownAdmin(ctx)
 }
 
 func WriteFailed(ctx echo.Context, err error) error {
@@ -227,23 +479,12 @@ func WriteJsonWithCode(ctx echo.Context, code int, data *response.Result) error
 	return ctx.JSON(code, data)
 }
 
+// RegisterRoute 向默认监听器（listeners.web）注册路由，其他监听器使用 Listen(name).RegisterRoute
 func RegisterRoute(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
-	route := &apiRoute{
-		method:     method,
-		path:       path,
-		handler:    handler,
-		middleware: middleware,
-	}
-	api := method + ":" + path
-	if _, ok := s.routes.Load(api); ok {
-		panic(api + " already exists.")
-	}
-	s.routes.Store(api, route)
+	Listen(constant.DefaultListener).RegisterRoute(method, path, handler, middleware...)
 }
 
-type apiRoute struct {
-	method     string
-	path       string
-	handler    echo.HandlerFunc
-	middleware []echo.MiddlewareFunc
+func routeNotFound(c echo.Context) error {
+	notFound := response.NewFailed("api not found", tidctx.WebTid(c))
+	return WriteJsonWithCode(c, http.StatusNotFound, notFound)
 }
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"errors"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/labstack/echo/v4"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startAndSignal 启动服务，after后模拟收到停机信号，返回启动错误
+func startAndSignal(t *testing.T, srv *Server, after time.Duration) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.StartGraceful()
+	}()
+	time.Sleep(after)
+	srv.quit <- syscall.SIGTERM
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+		return nil
+	}
+}
+
+func TestStopWaitsForSlowPrepare(t *testing.T) {
+	log := &eventLog{}
+	withLifecycles(t,
+		&fakeLifecycle{title: "a", log: log},
+		&fakeLifecycle{title: "b", delay: 200 * time.Millisecond, log: log},
+	)
+
+	if err := startAndSignal(t, newTestServer(), 50*time.Millisecond); err != nil {
+		t.Fatalf("unexpected start error: %v", err)
+	}
+	want := []string{"prepare:a", "prepare:b", "destroy:b", "destroy:a"}
+	if got := log.list(); !equalStrings(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if IsReady() {
+		t.Fatal("server must not become ready after stop began")
+	}
+}
+
+func TestStopDuringFailingPrepareDestroysOnce(t *testing.T) {
+	log := &eventLog{}
+	prepareErr := errors.New("boom")
+	withLifecycles(t,
+		&fakeLifecycle{title: "a", log: log},
+		&fakeLifecycle{title: "b", delay: 200 * time.Millisecond, err: prepareErr, log: log},
+	)
+
+	err := startAndSignal(t, newTestServer(), 50*time.Millisecond)
+	if !errors.Is(err, prepareErr) {
+		t.Fatalf("start error = %v, want %v", err, prepareErr)
+	}
+	if n := log.count("destroy:a"); n != 1 {
+		t.Fatalf("a destroyed %d times, want 1: %v", n, log.list())
+	}
+	if n := log.count("destroy:b"); n != 0 {
+		t.Fatalf("b failed to prepare and must not be destroyed: %v", log.list())
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// freePort 获取一个当前未被占用的端口
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// testListener 创建绑定在本地随机端口的监听器
+func testListener(t *testing.T, name string) *Listener {
+	t.Helper()
+	conf := config.GetWrapper(config.MakeKey(constant.ListenersConfig, name))
+	conf.Set("address", "127.0.0.1")
+	conf.Set("port", freePort(t))
+	l, err := newListener(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestAdminAnswersNotReadyDuringDrain(t *testing.T) {
+	withLifecycles(t)
+	admin := testListener(t, constant.AdminListener)
+	web := testListener(t, "drain")
+	t.Cleanup(func() {
+		routers.Delete("drain")
+	})
+	Listen("drain").RegisterRoute(http.MethodGet, "/slow", func(c echo.Context) error {
+		time.Sleep(500 * time.Millisecond)
+		return c.NoContent(http.StatusOK)
+	})
+	srv := newTestServer()
+	srv.listeners = []*Listener{admin, web}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.StartGraceful()
+	}()
+	deadline := time.Now().Add(3 * time.Second)
+	for !IsReady() {
+		if time.Now().After(deadline) {
+			t.Fatal("server did not become ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	slow := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + web.address() + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		slow <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	srv.quit <- syscall.SIGTERM
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get("http://" + admin.address() + "/health/ready")
+	if err != nil {
+		t.Fatalf("admin listener must answer during drain: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("/health/ready = %d during drain, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if err := <-slow; err != nil {
+		t.Fatalf("in-flight request failed: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected start error: %v", err)
+	}
+	if _, err := http.Get("http://" + admin.address() + "/health/live"); err == nil {
+		t.Fatal("admin listener must be shut down after stop")
+	}
+}
+
+func TestPreStopDelaySkippedWhenNeverReady(t *testing.T) {
+	webConfig := config.GetWrapper(config.MakeKey(constant.ListenersConfig, constant.DefaultListener))
+	webConfig.Set("pre_stop_delay", "3s")
+	t.Cleanup(func() {
+		webConfig.Set("pre_stop_delay", "0s")
+	})
+	prepareErr := errors.New("boom")
+	withLifecycles(t, &fakeLifecycle{title: "a", err: prepareErr, log: &eventLog{}})
+
+	begin := time.Now()
+	err := newTestServer().StartGraceful()
+	if !errors.Is(err, prepareErr) {
+		t.Fatalf("start error = %v, want %v", err, prepareErr)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Fatalf("stop took %s, pre_stop_delay must be skipped when the server never became ready", elapsed)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"github.com/chnyangzhen/kago-fly/pkg/response"
+	"github.com/chnyangzhen/kago-fly/pkg/tidctx"
+	"github.com/labstack/echo/v4"
+	"reflect"
+)
+
+var binder = &echo.DefaultBinder{}
+
+// Handle 将普通函数适配为echo.HandlerFunc：
+// 依次绑定path、query、header、body参数到Req，使用DataValidator校验，
+// 调用fn后将结果包装为带tid的response.Result；错误交由HTTPErrorHandler统一转换。
+//
+// 如：server.RegisterRoute("POST", "/user", server.Handle(user.Post))
+func Handle[Req, Resp any](fn func(ctx context.Context, req *Req) (*Resp, error)) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := new(Req)
+		if err := bind(c, req); err != nil {
+			return err
+		}
+		if reflect.Indirect(reflect.ValueOf(req)).Kind() == reflect.Struct {
+			if err := dataValidator.ValidateStruct(req); err != nil {
+				return err
+			}
+		}
+
+		resp, err := fn(tidctx.WrapWebCtx(c), req)
+		if err != nil {
+			return err
+		}
+		return WriteSuccess(c, resp)
+	}
+}
+
+// bind 绑定请求参数，绑定失败统一转换为参数错误
+func bind(c echo.Context, req interface{}) error {
+	if err := binder.BindPathParams(c, req); err != nil {
+		return toParamError(err)
+	}
+	if err := binder.BindQueryParams(c, req); err != nil {
+		return toParamError(err)
+	}
+	if err := binder.BindHeaders(c, req); err != nil {
+		return toParamError(err)
+	}
+	if err := binder.BindBody(c, req); err != nil {
+		return toParamError(err)
+	}
+	return nil
+}
+
+func toParamError(err error) error {
+	if he, ok := err.(*echo.HTTPError); ok {
+		if he.Internal != nil {
+			return response.NewParamError(he.Internal.Error())
+		}
+		return response.NewParamError(he.Error())
+	}
+	return response.NewParamError(err.Error())
+}
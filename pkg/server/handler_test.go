@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/chnyangzhen/kago-fly/pkg/response"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type handlerReq struct {
+	ID      int    `param:"id"`
+	Verbose bool   `query:"verbose"`
+	Token   string `header:"X-Token"`
+	Name    string `json:"name" validate:"required"`
+}
+
+type handlerResp struct {
+	ID      int    `json:"id"`
+	Verbose bool   `json:"verbose"`
+	Token   string `json:"token"`
+	Name    string `json:"name"`
+}
+
+type handlerResult struct {
+	Result  *handlerResp `json:"result"`
+	Msg     string       `json:"msg"`
+	Success bool         `json:"success"`
+	Tid     string       `json:"tid"`
+}
+
+// newRouteHandler 创建监听器并挂载通过register注册的路由，每个测试使用独立的监听器名称
+func newRouteHandler(t *testing.T, name string, register func(r *Router)) http.Handler {
+	t.Helper()
+	t.Cleanup(func() {
+		routers.Delete(name)
+	})
+	register(Listen(name))
+	l := testListener(t, name)
+	l.mountRoutes()
+	return l
+}
+
+func serve(h http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandle(t *testing.T) {
+	h := newRouteHandler(t, "handle", func(r *Router) {
+		r.RegisterRoute(http.MethodPost, "/users/:id", Handle(func(ctx context.Context, req *handlerReq) (*handlerResp, error) {
+			if req.Name == "fail" {
+				return nil, response.NewError(2, "user_error", "user failed")
+			}
+			return &handlerResp{ID: req.ID, Verbose: req.Verbose, Token: req.Token, Name: req.Name}, nil
+		}))
+	})
+
+	tests := []struct {
+		name    string
+		target  string
+		body    string
+		success bool
+		want    *handlerResp
+		msg     string
+	}{
+		{
+			name:    "binds path, query, header and body",
+			target:  "/users/7?verbose=true",
+			body:    `{"name":"alice"}`,
+			success: true,
+			want:    &handlerResp{ID: 7, Verbose: true, Token: "t-1", Name: "alice"},
+		},
+		{name: "bind error", target: "/users/abc", body: `{"name":"alice"}`, msg: "abc"},
+		{name: "invalid body", target: "/users/7", body: `{"name":`, msg: "unexpected EOF"},
+		{name: "validation error", target: "/users/7", body: `{}`, msg: "Name is a required field"},
+		{name: "handler error", target: "/users/7", body: `{"name":"fail"}`, msg: "user failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h, http.MethodPost, tt.target, tt.body, map[string]string{"X-Token": "t-1"})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			var got handlerResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode %q: %v", rec.Body.String(), err)
+			}
+			if got.Tid == "" {
+				t.Fatalf("response has no tid: %s", rec.Body.String())
+			}
+			if got.Success != tt.success {
+				t.Fatalf("success = %v, want %v: %s", got.Success, tt.success, rec.Body.String())
+			}
+			if tt.success {
+				if got.Result == nil || *got.Result != *tt.want {
+					t.Fatalf("result = %+v, want %+v", got.Result, tt.want)
+				}
+				return
+			}
+			if got.Result != nil || !strings.Contains(got.Msg, tt.msg) {
+				t.Fatalf("msg = %q, want it to contain %q: %s", got.Msg, tt.msg, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/chnyangzhen/kago-fly/pkg/response"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	HealthUp   = "UP"
+	HealthDown = "DOWN"
+
+	defaultHealthTimeout = 3 * time.Second
+)
+
+var (
+	// ready 就绪状态：StartedAfter完成后为true，stop开始时为false
+	ready        int32
+	healthChecks sync.Map
+)
+
+type (
+	// HealthChecker 健康检查函数，返回error表示不健康
+	HealthChecker func(ctx context.Context) error
+
+	// Checker 实现该接口的生命周期组件通过AddLifecycle自动注册健康检查，名称为Title()
+	Checker interface {
+		HealthCheck(ctx context.Context) error
+		Title() string
+	}
+
+	// HealthStatus 单项健康检查结果
+	HealthStatus struct {
+		Name    string `json:"name"`
+		Status  string `json:"status"`
+		Latency string `json:"latency"`
+		Error   string `json:"error,omitempty"`
+	}
+
+	// HealthDetail 健康检查详情
+	HealthDetail struct {
+		Status string          `json:"status"`
+		Ready  bool            `json:"ready"`
+		Checks []*HealthStatus `json:"checks"`
+	}
+)
+
+func init() {
+	admin := Listen(constant.AdminListener)
+	admin.RegisterRoute(http.MethodGet, "/health/live", healthLive)
+	admin.RegisterRoute(http.MethodGet, "/health/ready", healthReady)
+	admin.RegisterRoute(http.MethodGet, "/health/detail", healthDetail)
+}
+
+// RegisterHealthCheck 注册命名的健康检查，同名检查会被覆盖
+func RegisterHealthCheck(name string, check HealthChecker) {
+	healthChecks.Store(name, check)
+}
+
+// IsReady 应用是否就绪
+func IsReady() bool {
+	return atomic.LoadInt32(&ready) == 1
+}
+
+func setReady(v bool) {
+	if v {
+		atomic.StoreInt32(&ready, 1)
+	} else {
+		atomic.StoreInt32(&ready, 0)
+	}
+}
+
+// CheckHealth 并发执行所有健康检查，就绪且所有检查通过时状态为UP
+func CheckHealth(ctx context.Context) *HealthDetail {
+	timeout := config.GetWrapper(config.MakeKey(constant.ListenersConfig, constant.AdminListener)).
+		GetDuration("health.timeout")
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	detail := &HealthDetail{Status: HealthUp, Ready: IsReady(), Checks: make([]*HealthStatus, 0)}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	healthChecks.Range(func(key, value interface{}) bool {
+		wg.Add(1)
+		go func(name string, check HealthChecker) {
+			defer wg.Done()
+			status := runHealthCheck(ctx, name, check)
+			mu.Lock()
+			detail.Checks = append(detail.Checks, status)
+			mu.Unlock()
+		}(key.(string), value.(HealthChecker))
+		return true
+	})
+	wg.Wait()
+
+	sort.Slice(detail.Checks, func(i, j int) bool {
+		return detail.Checks[i].Name < detail.Checks[j].Name
+	})
+	for _, c := range detail.Checks {
+		if c.Status != HealthUp {
+			detail.Status = HealthDown
+		}
+	}
+	if !detail.Ready {
+		detail.Status = HealthDown
+	}
+	return detail
+}
+
+func runHealthCheck(ctx context.Context, name string, check HealthChecker) *HealthStatus {
+	start := time.Now()
+	status := &HealthStatus{Name: name, Status: HealthUp}
+
+	// 检查函数可能不响应ctx，超时后直接返回，检查协程自行结束
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			status.Status = HealthDown
+			status.Error = err.Error()
+		}
+	case <-ctx.Done():
+		status.Status = HealthDown
+		status.Error = ctx.Err().Error()
+	}
+	status.Latency = time.Since(start).String()
+	return status
+}
+
+func healthLive(c echo.Context) error {
+	return WriteSuccess(c, map[string]string{"status": HealthUp})
+}
+
+func healthReady(c echo.Context) error {
+	detail := CheckHealth(c.Request().Context())
+	if detail.Status != HealthUp {
+		return WriteJsonWithCode(c, http.StatusServiceUnavailable,
+			response.NewFailed("not ready", ""))
+	}
+	return WriteSuccess(c, map[string]string{"status": HealthUp})
+}
+
+func healthDetail(c echo.Context) error {
+	detail := CheckHealth(c.Request().Context())
+	code := http.StatusOK
+	if detail.Status != HealthUp {
+		code = http.StatusServiceUnavailable
+	}
+	r := response.NewSuccess(detail, "")
+	r.Success = detail.Status == HealthUp
+	return WriteJsonWithCode(c, code, r)
+}
@@ -2,13 +2,30 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/chnyangzhen/kago-fly/pkg/helper"
+	"github.com/chnyangzhen/kago-fly/pkg/logger"
 	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLifecycleTimeout = 30 * time.Second
+	defaultLifecycleWorkers = 4
 )
 
 var (
 	prepares = make([]Preparer, 0, 8)
 	destroys = make([]Destroyer, 0, 8)
 	afters   = make([]After, 0, 8)
+	// titles 按注册顺序记录的生命周期组件名称，同名组件视为同一个组件
+	titles = make([]string, 0, 8)
+	// priorities、dependencies 组件声明的优先级与依赖
+	priorities   = make(map[string]int)
+	dependencies = make(map[string][]string)
 )
 
 type (
@@ -26,27 +43,215 @@ type (
 		Title() string
 	}
 
+	// PreparerContext 支持超时控制的Preparer，超时时间由 lifecycle.timeouts.{title} 或 lifecycle.timeout 配置
+	PreparerContext interface {
+		OnPrepareContext(ctx context.Context) error
+		Title() string
+	}
+
+	// AfterContext 支持超时控制的After
+	AfterContext interface {
+		OnAfterContext(ctx context.Context) error
+		Title() string
+	}
+
 	// Destroyer 应用销毁前处理函数，如Apollo关闭连接、Logger关闭文件等等
 	Destroyer interface {
 		OnDestroy(ctx context.Context) error
 		Title() string
 	}
+
+	// Prioritized 声明生命周期优先级，数值越小越先启动、越后销毁，未声明时为0
+	Prioritized interface {
+		Priority() int
+	}
+
+	// Dependent 声明依赖的生命周期（Title），被依赖的组件先启动、后销毁
+	Dependent interface {
+		DependsOn() []string
+	}
 )
 
 // RegisterPrepare 注册Prepare
 func RegisterPrepare(prepare Preparer) {
+	register(prepare)
 	prepares = append(prepares, prepare)
 }
 
 // RegisterDestroy 注册Destroy
 func RegisterDestroy(destroy Destroyer) {
+	register(destroy)
 	destroys = append(destroys, destroy)
 }
 
 func RegisterStartedAfter(after After) {
+	register(after)
 	afters = append(afters, after)
 }
 
+// RegisterPrepareContext 注册支持超时控制的Prepare
+func RegisterPrepareContext(prepare PreparerContext) {
+	register(prepare)
+	prepares = append(prepares, &preparerContext{prepare})
+}
+
+// RegisterStartedAfterContext 注册支持超时控制的After
+func RegisterStartedAfterContext(after AfterContext) {
+	register(after)
+	afters = append(afters, &afterContext{after})
+}
+
+// preparerContext 将PreparerContext适配为Preparer，执行时优先调用OnPrepareContext
+type preparerContext struct {
+	PreparerContext
+}
+
+func (p *preparerContext) OnPrepare() error {
+	return p.OnPrepareContext(context.Background())
+}
+
+// afterContext 将AfterContext适配为After，执行时优先调用OnAfterContext
+type afterContext struct {
+	AfterContext
+}
+
+func (a *afterContext) OnAfter() error {
+	return a.OnAfterContext(context.Background())
+}
+
+// register 记录组件名称、优先级与依赖，用于启动前的排序
+func register(l Lifecycle) {
+	title := l.Title()
+	if !helper.ContainsString(titles, title) {
+		titles = append(titles, title)
+	}
+	registry.register(title)
+	if v, ok := l.(Prioritized); ok {
+		priorities[title] = v.Priority()
+	}
+	if v, ok := l.(Dependent); ok {
+		dependencies[title] = v.DependsOn()
+	}
+}
+
+// SortLifecycle 按依赖关系拓扑排序生命周期，无依赖关系的组件按优先级、注册顺序排列。
+// 依赖不存在或存在循环依赖时返回错误。Prepare、After按排序结果执行，Destroy严格逆序执行
+func SortLifecycle() error {
+	order, err := sortTitles(titles, priorities, dependencies)
+	if err != nil {
+		return err
+	}
+	rank := make(map[string]int, len(order))
+	for i, title := range order {
+		rank[title] = i
+	}
+	sort.SliceStable(prepares, func(i, j int) bool {
+		return rank[prepares[i].Title()] < rank[prepares[j].Title()]
+	})
+	sort.SliceStable(afters, func(i, j int) bool {
+		return rank[afters[i].Title()] < rank[afters[j].Title()]
+	})
+	sort.SliceStable(destroys, func(i, j int) bool {
+		return rank[destroys[i].Title()] < rank[destroys[j].Title()]
+	})
+	return nil
+}
+
+// sortTitles Kahn算法拓扑排序，每一轮从入度为0的组件中选择优先级最小、注册最早的组件
+func sortTitles(titles []string, priorities map[string]int, dependencies map[string][]string) ([]string, error) {
+	index := make(map[string]int, len(titles))
+	for i, title := range titles {
+		index[title] = i
+	}
+	inDegree := make(map[string]int, len(titles))
+	dependents := make(map[string][]string, len(titles))
+	for _, title := range titles {
+		for _, dep := range dependencies[title] {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("lifecycle: %s depends on unregistered lifecycle: %s", title, dep)
+			}
+			if dep == title {
+				return nil, fmt.Errorf("lifecycle: %s depends on itself", title)
+			}
+			inDegree[title]++
+			dependents[dep] = append(dependents[dep], title)
+		}
+	}
+
+	order := make([]string, 0, len(titles))
+	ready := make([]string, 0, len(titles))
+	for _, title := range titles {
+		if inDegree[title] == 0 {
+			ready = append(ready, title)
+		}
+	}
+	for len(ready) > 0 {
+		sort.SliceStable(ready, func(i, j int) bool {
+			pi, pj := priorities[ready[i]], priorities[ready[j]]
+			if pi != pj {
+				return pi < pj
+			}
+			return index[ready[i]] < index[ready[j]]
+		})
+		title := ready[0]
+		ready = ready[1:]
+		order = append(order, title)
+		for _, next := range dependents[title] {
+			inDegree[next]--
+			if inDegree[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+
+	if len(order) != len(titles) {
+		return nil, fmt.Errorf("lifecycle: circular dependency detected: %s", findCycle(titles, dependencies))
+	}
+	return order, nil
+}
+
+// findCycle 查找一条循环依赖路径，如：a -> b -> a
+func findCycle(titles []string, dependencies map[string][]string) string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(titles))
+	path := make([]string, 0, len(titles))
+	var cycle []string
+	var visit func(title string) bool
+	visit = func(title string) bool {
+		state[title] = visiting
+		path = append(path, title)
+		for _, dep := range dependencies[title] {
+			switch state[dep] {
+			case visiting:
+				for i, t := range path {
+					if t == dep {
+						cycle = append(append([]string{}, path[i:]...), dep)
+						break
+					}
+				}
+				return true
+			case unvisited:
+				if visit(dep) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[title] = visited
+		return false
+	}
+	for _, title := range titles {
+		if state[title] == unvisited && visit(title) {
+			break
+		}
+	}
+	return strings.Join(cycle, " -> ")
+}
+
 // AfterLifecycle 返回After列表的副本
 func AfterLifecycle() []After {
 	dst := make([]After, len(afters))
@@ -61,12 +266,99 @@ func PrepareLifecycle() []Preparer {
 	return dst
 }
 
-// DestroyLifecycle 返回Destroy列表的副本
+// DestroyLifecycle 返回Destroy列表的副本，顺序与启动顺序严格相反
 func DestroyLifecycle() []Destroyer {
 	dst := make([]Destroyer, len(destroys))
-	copy(dst, destroys)
-	sort.Slice(dst, func(i, j int) bool {
-		return i > j
-	})
+	for i, destroy := range destroys {
+		dst[len(destroys)-1-i] = destroy
+	}
 	return dst
 }
+
+// lifecycleTimeout 组件生命周期函数的超时时间：lifecycle.timeouts.{title} > lifecycle.timeout > 默认30s
+func lifecycleTimeout(title string) time.Duration {
+	conf := config.GetWrapper(constant.LifecycleConfig)
+	if timeout := conf.GetDuration(config.MakeKey("timeouts", title)); timeout > 0 {
+		return timeout
+	}
+	if timeout := conf.GetDuration("timeout"); timeout > 0 {
+		return timeout
+	}
+	return defaultLifecycleTimeout
+}
+
+// runHook 在超时控制下执行生命周期函数，超时、parent结束或panic时返回错误。
+// 不支持context的生命周期函数超时后仍在后台运行，但不再阻塞启动、停机流程
+func runHook(parent context.Context, title string, hook func(ctx context.Context) error) error {
+	timeout := lifecycleTimeout(title)
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("lifecycle: %s panic: %v", title, r)
+			}
+		}()
+		done <- hook(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if err := parent.Err(); err != nil {
+			return fmt.Errorf("lifecycle: %s aborted: %w", title, err)
+		}
+		return fmt.Errorf("lifecycle: %s timeout after %s", title, timeout)
+	}
+}
+
+// prepareOne 执行单个组件的Prepare并记录耗时
+func prepareOne(prepare Preparer) error {
+	title := prepare.Title()
+	logger.Infof("Prepare lifecycle title: %s is ready.", title)
+	registry.transition(title, StatePreparing, nil)
+	start := time.Now()
+	err := runHook(context.Background(), title, prepareHook(prepare))
+	elapsed := time.Since(start)
+	registry.setPrepareCost(title, elapsed)
+	if err != nil {
+		registry.transition(title, StateFailed, err)
+		logger.Errorf("Prepare lifecycle title: %s error with %s, elapsed: %s", title, err.Error(), elapsed)
+		return err
+	}
+	registry.transition(title, StateReady, nil)
+	logger.Infof("Prepare lifecycle title: %s completed, elapsed: %s", title, elapsed)
+	return nil
+}
+
+// PrepareTimings 返回各组件Prepare的耗时，用于诊断启动耗时
+func PrepareTimings() map[string]time.Duration {
+	timings := make(map[string]time.Duration)
+	for _, status := range registry.list() {
+		if status.PreparingAt != nil {
+			timings[status.Title] = status.prepareCost
+		}
+	}
+	return timings
+}
+
+func prepareHook(prepare Preparer) func(ctx context.Context) error {
+	if v, ok := prepare.(PreparerContext); ok {
+		return v.OnPrepareContext
+	}
+	return func(ctx context.Context) error {
+		return prepare.OnPrepare()
+	}
+}
+
+func afterHook(after After) func(ctx context.Context) error {
+	if v, ok := after.(AfterContext); ok {
+		return v.OnAfterContext
+	}
+	return func(ctx context.Context) error {
+		return after.OnAfter()
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LifecycleState 生命周期组件状态
+type LifecycleState string
+
+const (
+	StateRegistered LifecycleState = "registered"
+	StatePreparing  LifecycleState = "preparing"
+	StateReady      LifecycleState = "ready"
+	StateFailed     LifecycleState = "failed"
+	StateDestroying LifecycleState = "destroying"
+	StateDestroyed  LifecycleState = "destroyed"
+)
+
+// LifecycleStatus 生命周期组件的运行状态
+type LifecycleStatus struct {
+	Title        string         `json:"title"`
+	State        LifecycleState `json:"state"`
+	Priority     int            `json:"priority"`
+	DependsOn    []string       `json:"dependsOn,omitempty"`
+	RegisteredAt time.Time      `json:"registeredAt"`
+	PreparingAt  *time.Time     `json:"preparingAt,omitempty"`
+	ReadyAt      *time.Time     `json:"readyAt,omitempty"`
+	FailedAt     *time.Time     `json:"failedAt,omitempty"`
+	DestroyingAt *time.Time     `json:"destroyingAt,omitempty"`
+	DestroyedAt  *time.Time     `json:"destroyedAt,omitempty"`
+	PrepareCost  string         `json:"prepareCost,omitempty"` // Prepare耗时
+	LastError    string         `json:"lastError,omitempty"`
+
+	prepareCost time.Duration
+}
+
+// lifecycleRegistry 记录所有生命周期组件的状态、状态变更时间与最近一次错误
+type lifecycleRegistry struct {
+	mu       sync.RWMutex
+	titles   []string
+	statuses map[string]*LifecycleStatus
+}
+
+var registry = &lifecycleRegistry{statuses: make(map[string]*LifecycleStatus)}
+
+func init() {
+	Listen(constant.AdminListener).RegisterRoute(http.MethodGet, "/lifecycles", lifecycleStatuses)
+}
+
+func (r *lifecycleRegistry) register(title string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.statuses[title]; ok {
+		return
+	}
+	r.titles = append(r.titles, title)
+	r.statuses[title] = &LifecycleStatus{
+		Title:        title,
+		State:        StateRegistered,
+		RegisteredAt: time.Now(),
+	}
+}
+
+// transition 变更组件状态，err不为nil时记录为最近一次错误
+func (r *lifecycleRegistry) transition(title string, state LifecycleState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status, ok := r.statuses[title]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	status.State = state
+	switch state {
+	case StatePreparing:
+		status.PreparingAt = &now
+	case StateReady:
+		status.ReadyAt = &now
+	case StateFailed:
+		status.FailedAt = &now
+	case StateDestroying:
+		status.DestroyingAt = &now
+	case StateDestroyed:
+		status.DestroyedAt = &now
+	}
+	if err != nil {
+		status.LastError = err.Error()
+	}
+}
+
+func (r *lifecycleRegistry) setPrepareCost(title string, cost time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if status, ok := r.statuses[title]; ok {
+		status.prepareCost = cost
+		status.PrepareCost = cost.String()
+	}
+}
+
+func (r *lifecycleRegistry) list() []LifecycleStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]LifecycleStatus, 0, len(r.titles))
+	for _, title := range r.titles {
+		status := *r.statuses[title]
+		status.Priority = priorities[title]
+		status.DependsOn = dependencies[title]
+		list = append(list, status)
+	}
+	return list
+}
+
+// LifecycleStatuses 返回所有生命周期组件的状态，按注册顺序排列
+func LifecycleStatuses() []LifecycleStatus {
+	return registry.list()
+}
+
+// GetLifecycleStatus 根据Title获取生命周期组件的状态
+func GetLifecycleStatus(title string) (LifecycleStatus, bool) {
+	for _, status := range registry.list() {
+		if status.Title == title {
+			return status, true
+		}
+	}
+	return LifecycleStatus{}, false
+}
+
+func lifecycleStatuses(c echo.Context) error {
+	return WriteSuccess(c, LifecycleStatuses())
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParallelPrepareFailureRollback(t *testing.T) {
+	conf := config.GetWrapper(constant.LifecycleConfig)
+	conf.Set("parallel", true)
+	conf.Set("workers", 8)
+	t.Cleanup(func() {
+		conf.Set("parallel", false)
+		conf.Set("workers", 0)
+	})
+	log := &eventLog{}
+	errE, errF := errors.New("e failed"), errors.New("f failed")
+	withLifecycles(t,
+		&fakeLifecycle{title: "a", log: log},
+		&fakeLifecycle{title: "b", delay: 50 * time.Millisecond, log: log},
+		&fakeLifecycle{title: "c", deps: []string{"a"}, delay: 200 * time.Millisecond, log: log},
+		&fakeLifecycle{title: "d", deps: []string{"c"}, log: log},
+		&fakeLifecycle{title: "e", delay: 150 * time.Millisecond, err: errE, log: log},
+		&fakeLifecycle{title: "f", delay: 100 * time.Millisecond, err: errF, log: log},
+	)
+
+	err := newTestServer().prepare()
+	// 第一个失败后执行中的组件仍会结束，其失败同样被汇总
+	if !errors.Is(err, errE) || !errors.Is(err, errF) {
+		t.Fatalf("prepare error = %v, want both %v and %v", err, errE, errF)
+	}
+	if !strings.Contains(err.Error(), "not started: d") {
+		t.Fatalf("prepare error = %v, want d listed as not started", err)
+	}
+	if log.count("prepare:d") != 0 {
+		t.Fatalf("d must not be scheduled after a failure: %v", log.list())
+	}
+
+	var destroyed []string
+	for _, event := range log.list() {
+		if strings.HasPrefix(event, "destroy:") {
+			destroyed = append(destroyed, event)
+		}
+	}
+	// 只回滚准备成功的组件，依赖方先于被依赖方
+	want := []string{"destroy:c", "destroy:b", "destroy:a"}
+	if !equalStrings(destroyed, want) {
+		t.Fatalf("rollback = %v, want %v", destroyed, want)
+	}
+	if _, ok := PrepareTimings()["d"]; ok {
+		t.Fatal("d was never started and must not have a prepare timing")
+	}
+}
+
+func TestSortTitles(t *testing.T) {
+	tests := []struct {
+		name         string
+		titles       []string
+		priorities   map[string]int
+		dependencies map[string][]string
+		want         []string
+		err          string
+	}{
+		{
+			name:   "registration order without priority",
+			titles: []string{"a", "b", "c"},
+			want:   []string{"a", "b", "c"},
+		},
+		{
+			name:       "priority among independent components",
+			titles:     []string{"a", "b", "c", "d"},
+			priorities: map[string]int{"a": 10, "b": -1, "d": 10},
+			want:       []string{"b", "c", "a", "d"},
+		},
+		{
+			name:         "dependency overrides priority",
+			titles:       []string{"db", "cache", "web"},
+			priorities:   map[string]int{"db": 100, "web": -100},
+			dependencies: map[string][]string{"web": {"db", "cache"}},
+			want:         []string{"cache", "db", "web"},
+		},
+		{
+			name:         "priority among components that become ready",
+			titles:       []string{"base", "x", "y"},
+			priorities:   map[string]int{"x": 5, "y": 1},
+			dependencies: map[string][]string{"x": {"base"}, "y": {"base"}},
+			want:         []string{"base", "y", "x"},
+		},
+		{
+			name:         "unknown dependency",
+			titles:       []string{"a", "b"},
+			dependencies: map[string][]string{"b": {"missing"}},
+			err:          "lifecycle: b depends on unregistered lifecycle: missing",
+		},
+		{
+			name:         "self dependency",
+			titles:       []string{"a"},
+			dependencies: map[string][]string{"a": {"a"}},
+			err:          "lifecycle: a depends on itself",
+		},
+		{
+			name:         "cycle",
+			titles:       []string{"a", "b", "c", "d"},
+			dependencies: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+			err:          "lifecycle: circular dependency detected: a -> b -> c -> a",
+		},
+		{
+			name:         "cycle reached through a dependent",
+			titles:       []string{"x", "a", "b"},
+			dependencies: map[string][]string{"x": {"a"}, "a": {"b"}, "b": {"a"}},
+			err:          "lifecycle: circular dependency detected: a -> b -> a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sortTitles(tt.titles, tt.priorities, tt.dependencies)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("sortTitles error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalStrings(got, tt.want) {
+				t.Fatalf("sortTitles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDestroyLifecycleReverseOrder(t *testing.T) {
+	log := &eventLog{}
+	withLifecycles(t,
+		&fakeLifecycle{title: "web", deps: []string{"db", "cache"}, log: log},
+		&fakeLifecycle{title: "cache", log: log},
+		&fakeLifecycle{title: "db", log: log},
+		&fakeLifecycle{title: "metrics", deps: []string{"web"}, log: log},
+	)
+
+	var prepared []string
+	for _, p := range PrepareLifecycle() {
+		prepared = append(prepared, p.Title())
+	}
+	if want := []string{"cache", "db", "web", "metrics"}; !equalStrings(prepared, want) {
+		t.Fatalf("prepare order = %v, want %v", prepared, want)
+	}
+	var destroyed []string
+	for _, d := range DestroyLifecycle() {
+		destroyed = append(destroyed, d.Title())
+	}
+	if want := []string{"metrics", "web", "db", "cache"}; !equalStrings(destroyed, want) {
+		t.Fatalf("destroy order = %v, want %v", destroyed, want)
+	}
+}
+
+// blockingDestroyer OnDestroy不响应ctx，直到release关闭
+type blockingDestroyer struct {
+	title   string
+	release chan struct{}
+}
+
+func (b *blockingDestroyer) Title() string {
+	return b.title
+}
+
+func (b *blockingDestroyer) OnDestroy(ctx context.Context) error {
+	<-b.release
+	return nil
+}
+
+// destroyOnly 只实现Destroy的组件
+type destroyOnly struct {
+	title string
+	log   *eventLog
+}
+
+func (d *destroyOnly) Title() string {
+	return d.title
+}
+
+func (d *destroyOnly) OnDestroy(ctx context.Context) error {
+	d.log.add("destroy:" + d.title)
+	return nil
+}
+
+func lifecycleState(title string) LifecycleStatus {
+	for _, status := range LifecycleStatuses() {
+		if status.Title == title {
+			return status
+		}
+	}
+	return LifecycleStatus{}
+}
+
+func TestDestroyHookTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration // lifecycle.timeouts.{title}
+		budget  time.Duration // 停机剩余时间
+		err     string
+	}{
+		{"hook timeout", 50 * time.Millisecond, 5 * time.Second, "timeout after 50ms"},
+		{"shutdown budget", 0, 50 * time.Millisecond, "aborted: context deadline exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &eventLog{}
+			stuck := &blockingDestroyer{title: "stuck-" + strings.ReplaceAll(tt.name, " ", "-"), release: make(chan struct{})}
+			defer close(stuck.release)
+			next := &destroyOnly{title: "next-" + strings.ReplaceAll(tt.name, " ", "-"), log: log}
+			withLifecycles(t, next, stuck)
+			if tt.timeout > 0 {
+				conf := config.GetWrapper(constant.LifecycleConfig)
+				conf.Set(config.MakeKey("timeouts", stuck.title), tt.timeout)
+				t.Cleanup(func() {
+					conf.Set(config.MakeKey("timeouts", stuck.title), 0)
+				})
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.budget)
+			defer cancel()
+			start := time.Now()
+			newTestServer().destroy(ctx)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("destroy took %s, a stuck hook must not block shutdown", elapsed)
+			}
+			status := lifecycleState(stuck.title)
+			if status.State != StateFailed || !strings.Contains(status.LastError, tt.err) {
+				t.Fatalf("%s status = %s %q, want failed with %q", stuck.title, status.State, status.LastError, tt.err)
+			}
+			// 后续组件仍会执行Destroy，停机时间耗尽时立即失败
+			if tt.timeout > 0 {
+				if log.count("destroy:"+next.title) != 1 || lifecycleState(next.title).State != StateDestroyed {
+					t.Fatalf("%s was not destroyed after the stuck hook timed out", next.title)
+				}
+			} else if state := lifecycleState(next.title).State; state != StateFailed {
+				t.Fatalf("%s state = %s, want failed once the shutdown budget is spent", next.title, state)
+			}
+		})
+	}
+}
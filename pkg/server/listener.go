@@ -0,0 +1,241 @@
+package server
+
+import (
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/chnyangzhen/kago-fly/pkg/helper"
+	"github.com/chnyangzhen/kago-fly/pkg/logger"
+	"github.com/chnyangzhen/kago-fly/pkg/response"
+	"github.com/chnyangzhen/kago-fly/pkg/tidctx"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// routers 每个监听器的路由表，key为监听器名称
+var routers sync.Map
+
+// Listener Web监听器，listeners下的每一项配置对应一个独立的echo实例
+type Listener struct {
+	*echo.Echo
+	name     string
+	config   *config.Configuration
+	options  listenerOptions
+	router   *Router
+	inflight sync.Map // 处理中的请求，停机超时时用于打印未完成的请求
+}
+
+// listenerOptions 监听器绑定地址，端口缺失时启动失败，避免监听随机端口
+type listenerOptions struct {
+	Address string `json:"address" default:"0.0.0.0"`
+	Port    int    `json:"port" validate:"required,min=1,max=65535"`
+}
+
+type inflightRequest struct {
+	method string
+	uri    string
+	tid    string
+	start  time.Time
+}
+
+// newListener 创建监听器，配置校验失败时返回 *config.BindError
+func newListener(name string) (*Listener, error) {
+	webConfig := config.GetWrapper(config.MakeKey(constant.ListenersConfig, name))
+	var options listenerOptions
+	if err := webConfig.Bind(&options); err != nil {
+		return nil, err
+	}
+	e := echo.New()
+
+	targetHeader := config.GetStringWithDefault("trace.id-key", constant.XRequestID)
+	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		Generator: func() string {
+			return helper.Uuid()
+		},
+		RequestIDHandler: func(ctx echo.Context, tid string) {
+			tidctx.InitWebTid(ctx, tid)
+		},
+
+		TargetHeader: targetHeader,
+	}))
+
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		if helper.IsNil(err) {
+			return
+		}
+		var r *response.Result
+		switch x := err.(type) {
+		case *response.InnerError:
+			r = response.NewInnerErrorFailedWith(x, tidctx.WebTid(c))
+		case *response.ParamError:
+			r = response.NewParamErrorWith(x, tidctx.WebTid(c))
+		default:
+			r = response.NewFailed("unknown error", tidctx.WebTid(c))
+		}
+		WriteJson(c, r)
+	}
+
+	e.RouteNotFound("/*", routeNotFound)
+
+	// 设置BodyLimit
+	if bodyLimit := webConfig.GetString("features.body_limit"); bodyLimit != "" {
+		logger.Infof("监听器: %s 开启BodyLimit限制, body-limit: size= %s", name, bodyLimit)
+		e.Pre(middleware.BodyLimit(bodyLimit))
+	}
+
+	// CORS（是否开启支持跨域访问特性）
+	if enabled := webConfig.GetBool("features.cors_enable"); enabled {
+		logger.Infof("监听器: %s 开启跨域访问", name)
+		e.Pre(middleware.CORS())
+	}
+
+	// CSRF（是否开启检查跨站请求伪造特性）
+	if enabled := webConfig.GetBool("features.csrf_enable"); enabled {
+		logger.Infof("监听器: %s 开启CSRF", name)
+		e.Pre(middleware.CSRF())
+	}
+
+	e.HideBanner = true
+	e.HidePort = true
+
+	// 捕获error，InnerError错误可以直接panic，不打印堆栈，其他错误需要打印堆栈
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		DisableStackAll:   true,
+		DisablePrintStack: true,
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			_, ok := err.(*response.ParamError)
+			if !ok {
+				tid := tidctx.WebTid(c)
+				logger.TraceId(tid).Errorw("recover error", "err", err, "stack", string(stack))
+			}
+			return err
+		},
+	}))
+	e.Validator = dataValidator
+
+	l := &Listener{
+		Echo:    e,
+		name:    name,
+		config:  webConfig,
+		options: options,
+		router:  Listen(name),
+	}
+	e.Use(l.trackInflight)
+	return l, nil
+}
+
+// isWorkerListener listeners下与Worker同名的配置项由Worker自行启动（如pprof），不创建Web监听器
+func isWorkerListener(name string) bool {
+	for _, worker := range WorkerLifecycle() {
+		if worker.Title() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// trackInflight 记录处理中的请求
+func (l *Listener) trackInflight(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		l.inflight.Store(req, &inflightRequest{
+			method: req.Method,
+			uri:    req.RequestURI,
+			tid:    tidctx.WebTid(c),
+			start:  time.Now(),
+		})
+		defer l.inflight.Delete(req)
+		return next(c)
+	}
+}
+
+// logInflight 打印仍未完成的请求
+func (l *Listener) logInflight() {
+	l.inflight.Range(func(key, value interface{}) bool {
+		r := value.(*inflightRequest)
+		logger.TraceId(r.tid).Warnw("request still in flight at shutdown deadline",
+			"listener", l.name, "method", r.method, "uri", r.uri, "elapsed", time.Since(r.start).String())
+		return true
+	})
+}
+
+// Name 监听器名称，即listeners下的配置项名称
+func (l *Listener) Name() string {
+	return l.name
+}
+
+func (l *Listener) address() string {
+	return l.options.Address + ":" + strconv.Itoa(l.options.Port)
+}
+
+// mountRoutes 将监听器路由表中的路由挂载到echo实例，分组路由挂载到对应的echo.Group
+func (l *Listener) mountRoutes() {
+	mounted := make(map[*RouteGroup]*echo.Group)
+	l.router.routes.Range(func(key, value interface{}) bool {
+		r := value.(*apiRoute)
+		if r.group == nil {
+			l.Add(r.method, r.path, r.handler, r.middleware...)
+		} else {
+			r.group.mount(l.Echo, mounted).Add(r.method, r.path, r.handler, r.middleware...)
+		}
+		return true
+	})
+}
+
+// Router 监听器的路由表，路由在监听器启动时挂载
+type Router struct {
+	listener string
+	routes   sync.Map
+}
+
+// Listen 获取指定监听器的路由表，如：server.Listen("admin").RegisterRoute("GET", "/metrics", handler)
+func Listen(name string) *Router {
+	r, _ := routers.LoadOrStore(name, &Router{listener: name})
+	return r.(*Router)
+}
+
+// RegisterRoute 向监听器注册路由，重复注册（method+path）会panic
+func (r *Router) RegisterRoute(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
+	r.addRoute(nil, method, path, handler, middleware...)
+}
+
+// Group 在监听器下创建路由分组
+func (r *Router) Group(prefix string, middleware ...echo.MiddlewareFunc) *RouteGroup {
+	return &RouteGroup{
+		router:     r,
+		prefix:     prefix,
+		middleware: middleware,
+	}
+}
+
+func (r *Router) addRoute(group *RouteGroup, method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
+	route := &apiRoute{
+		group:      group,
+		method:     method,
+		path:       path,
+		handler:    handler,
+		middleware: middleware,
+	}
+	api := method + ":" + route.fullPath()
+	if _, ok := r.routes.Load(api); ok {
+		panic(r.listener + " " + api + " already exists.")
+	}
+	r.routes.Store(api, route)
+}
+
+type apiRoute struct {
+	group      *RouteGroup
+	method     string
+	path       string
+	handler    echo.HandlerFunc
+	middleware []echo.MiddlewareFunc
+}
+
+func (r *apiRoute) fullPath() string {
+	if r.group == nil {
+		return r.path
+	}
+	return r.group.fullPrefix() + r.path
+}
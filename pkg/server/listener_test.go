@@ -0,0 +1,54 @@
+package server
+
+import (
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListenerBindErrorAbortsStartup(t *testing.T) {
+	withLifecycles(t)
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	conf := config.GetWrapper(config.MakeKey(constant.ListenersConfig, "busy"))
+	conf.Set("address", "127.0.0.1")
+	conf.Set("port", busy.Addr().(*net.TCPAddr).Port)
+	l, err := newListener("busy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer()
+	srv.listeners = []*Listener{l}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.StartGraceful()
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "listener busy") {
+			t.Fatalf("start error = %v, want bind error of listener busy", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("startup did not abort on bind error")
+	}
+	if IsReady() {
+		t.Fatal("server must not be ready when a listener failed to start")
+	}
+}
+
+func TestWorkerListenersAreSkipped(t *testing.T) {
+	if !isWorkerListener("pprof") {
+		t.Fatal("pprof is started by its worker and must not create a web listener")
+	}
+	if isWorkerListener(constant.DefaultListener) {
+		t.Fatal("web is not a worker")
+	}
+}
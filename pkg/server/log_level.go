@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/chnyangzhen/kago-fly/pkg/logger"
+	"github.com/chnyangzhen/kago-fly/pkg/response"
+	"github.com/chnyangzhen/kago-fly/pkg/tidctx"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"time"
+)
+
+func init() {
+	Listen(constant.AdminListener).RegisterRoute(http.MethodGet, "/log/level", logLevel)
+	Listen(constant.AdminListener).RegisterRoute(http.MethodPut, "/log/level", Handle(setLogLevel))
+	Listen(constant.AdminListener).RegisterRoute(http.MethodDelete, "/log/level", resetLogLevel)
+}
+
+// LogLevelRequest 修改日志级别，Logger为空时修改根日志，TTL为空时持续到日志配置文件变更，
+// 如：{"logger": "payment", "level": "debug", "ttl": "10m"}
+type LogLevelRequest struct {
+	Logger string `json:"logger"`
+	Level  string `json:"level" validate:"required"`
+	TTL    string `json:"ttl"`
+}
+
+func logLevel(c echo.Context) error {
+	return WriteSuccess(c, logger.GetLevelStatus())
+}
+
+func setLogLevel(ctx context.Context, req *LogLevelRequest) (*logger.LevelStatus, error) {
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		return nil, response.NewParamError(err.Error())
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+			return nil, response.NewParamError("invalid ttl: " + req.TTL)
+		}
+	}
+	logger.SetNamedLevel(req.Logger, level, ttl)
+	logger.Trace(ctx).Infow("log level changed", "name", req.Logger, "level", level.String(), "ttl", ttl.String())
+	status := logger.GetLevelStatus()
+	return &status, nil
+}
+
+// resetLogLevel 取消运行时修改的日志级别，恢复为日志配置文件中的级别，如：DELETE /log/level?logger=payment
+func resetLogLevel(c echo.Context) error {
+	name := c.QueryParam("logger")
+	logger.ResetLevel(name)
+	logger.Trace(tidctx.WrapWebCtx(c)).Infow("log level reset", "name", name)
+	return WriteSuccess(c, logger.GetLevelStatus())
+}
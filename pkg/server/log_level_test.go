@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/chnyangzhen/kago-fly/pkg/logger"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// levelResponse /log/level 的响应
+type levelResponse struct {
+	Result  logger.LevelStatus `json:"result"`
+	Msg     string             `json:"msg"`
+	Success bool               `json:"success"`
+}
+
+// newAdminHandler 挂载管理监听器的路由，不监听端口
+func newAdminHandler(t *testing.T) http.Handler {
+	t.Helper()
+	admin := testListener(t, constant.AdminListener)
+	admin.mountRoutes()
+	t.Cleanup(func() {
+		logger.ResetLevel("")
+		logger.ResetLevel("payment")
+	})
+	return admin
+}
+
+func callLogLevel(t *testing.T, h http.Handler, method, target, body string) levelResponse {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s %s: status %d: %s", method, target, rec.Code, rec.Body.String())
+	}
+	var resp levelResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: decode %q: %v", method, target, rec.Body.String(), err)
+	}
+	return resp
+}
+
+// waitLevel 等待临时修改到期后恢复
+func waitLevel(t *testing.T, name string, want zapcore.Level) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for logger.NamedLevel(name) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("level of %q = %s, want %s after ttl", name, logger.NamedLevel(name), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLogLevelGetPutDelete(t *testing.T) {
+	h := newAdminHandler(t)
+
+	resp := callLogLevel(t, h, http.MethodGet, "/log/level", "")
+	if !resp.Success || resp.Result.Level != "info" || resp.Result.Configured != "info" {
+		t.Fatalf("GET = %+v, want configured info level", resp)
+	}
+
+	resp = callLogLevel(t, h, http.MethodPut, "/log/level", `{"level": "debug"}`)
+	if !resp.Success || resp.Result.Level != "debug" || resp.Result.Configured != "info" || resp.Result.ExpiresAt != nil {
+		t.Fatalf("PUT root = %+v, want debug without expiry", resp)
+	}
+	if logger.Level() != zapcore.DebugLevel {
+		t.Fatalf("root level = %s, want debug", logger.Level())
+	}
+
+	resp = callLogLevel(t, h, http.MethodPut, "/log/level", `{"logger": "payment", "level": "error"}`)
+	if got := resp.Result.Loggers["payment"].Level; !resp.Success || got != "error" {
+		t.Fatalf("PUT payment = %+v, want error", resp)
+	}
+	if got := logger.NamedLevel("payment.refund"); got != zapcore.ErrorLevel {
+		t.Fatalf("payment.refund level = %s, want inherited error", got)
+	}
+
+	resp = callLogLevel(t, h, http.MethodDelete, "/log/level?logger=payment", "")
+	if _, ok := resp.Result.Loggers["payment"]; !resp.Success || ok {
+		t.Fatalf("DELETE payment = %+v, want payment removed", resp)
+	}
+	if got := logger.NamedLevel("payment"); got != zapcore.DebugLevel {
+		t.Fatalf("payment level = %s, want inherited root level debug", got)
+	}
+
+	resp = callLogLevel(t, h, http.MethodDelete, "/log/level", "")
+	if !resp.Success || resp.Result.Level != "info" {
+		t.Fatalf("DELETE root = %+v, want configured info level", resp)
+	}
+}
+
+func TestLogLevelRejectsInvalidRequests(t *testing.T) {
+	h := newAdminHandler(t)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"unknown level", `{"level": "loud"}`},
+		{"missing level", `{"logger": "payment"}`},
+		{"invalid ttl", `{"level": "debug", "ttl": "soon"}`},
+		{"negative ttl", `{"level": "debug", "ttl": "-1s"}`},
+		{"malformed json", `{"level": `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := callLogLevel(t, h, http.MethodPut, "/log/level", tt.body)
+			if resp.Success {
+				t.Fatalf("PUT %s succeeded: %+v", tt.body, resp)
+			}
+			if logger.Level() != zapcore.InfoLevel || logger.NamedLevel("payment") != zapcore.InfoLevel {
+				t.Fatalf("rejected request changed the level: root %s, payment %s", logger.Level(), logger.NamedLevel("payment"))
+			}
+		})
+	}
+}
+
+func TestLogLevelTTLExpiry(t *testing.T) {
+	h := newAdminHandler(t)
+
+	resp := callLogLevel(t, h, http.MethodPut, "/log/level", `{"level": "warn", "ttl": "100ms"}`)
+	if !resp.Success || resp.Result.Level != "warn" || resp.Result.ExpiresAt == nil {
+		t.Fatalf("PUT with ttl = %+v, want warn with expiry", resp)
+	}
+	resp = callLogLevel(t, h, http.MethodPut, "/log/level", `{"logger": "payment", "level": "debug", "ttl": "100ms"}`)
+	if got := resp.Result.Loggers["payment"]; got.Level != "debug" || got.ExpiresAt == nil {
+		t.Fatalf("PUT payment with ttl = %+v, want debug with expiry", got)
+	}
+
+	waitLevel(t, "", zapcore.InfoLevel)
+	waitLevel(t, "payment", zapcore.InfoLevel)
+	resp = callLogLevel(t, h, http.MethodGet, "/log/level", "")
+	if resp.Result.ExpiresAt != nil {
+		t.Fatalf("GET after ttl = %+v, expiry must be cleared", resp.Result)
+	}
+	if _, ok := resp.Result.Loggers["payment"]; ok {
+		t.Fatalf("GET after ttl = %+v, reverted payment must be removed", resp.Result)
+	}
+}
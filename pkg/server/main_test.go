@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/logger"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testApplication = `
+lifecycle:
+  timeout: 2s
+`
+
+const testLogger = `
+default:
+  level: "info"
+  encoding: "console"
+  encoderConfig:
+    messageKey: "message"
+    levelKey: "level"
+    levelEncoder: "capital"
+  outputPaths: ["stderr"]
+rolling:
+  logFilePath: "%s"
+  errorFileName: "error.log"
+`
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "kago-server-test")
+	if err != nil {
+		panic(err)
+	}
+	code := func() int {
+		defer os.RemoveAll(dir)
+		if err := os.WriteFile(filepath.Join(dir, "application.yml"), []byte(testApplication), 0o644); err != nil {
+			panic(err)
+		}
+		loggerFile := filepath.Join(dir, "logger.yml")
+		if err := os.WriteFile(loggerFile, []byte(fmt.Sprintf(testLogger, dir)), 0o644); err != nil {
+			panic(err)
+		}
+		if err := config.InitConfig([]string{"application"}, config.WithConfigDirs(dir)); err != nil {
+			panic(err)
+		}
+		if err := logger.InitLogger(loggerFile); err != nil {
+			panic(err)
+		}
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+// newTestServer 与包初始化时创建的Server一致，测试之间互不影响
+func newTestServer() *Server {
+	return &Server{
+		prepared: make(map[string]bool),
+		started:  make(chan struct{}),
+		quit:     make(chan os.Signal, 1),
+	}
+}
+
+// withLifecycles 替换已注册的生命周期组件，测试结束后恢复
+func withLifecycles(t *testing.T, lifecycles ...Lifecycle) {
+	t.Helper()
+	savedPrepares, savedDestroys, savedAfters := prepares, destroys, afters
+	savedTitles, savedPriorities, savedDependencies := titles, priorities, dependencies
+	t.Cleanup(func() {
+		prepares, destroys, afters = savedPrepares, savedDestroys, savedAfters
+		titles, priorities, dependencies = savedTitles, savedPriorities, savedDependencies
+	})
+	prepares, destroys, afters = nil, nil, nil
+	titles, priorities, dependencies = nil, make(map[string]int), make(map[string][]string)
+	for _, l := range lifecycles {
+		AddLifecycle(l)
+	}
+	if err := SortLifecycle(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// eventLog 按发生顺序记录生命周期事件
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *eventLog) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *eventLog) list() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string{}, e.events...)
+}
+
+func (e *eventLog) count(event string) int {
+	n := 0
+	for _, ev := range e.list() {
+		if ev == event {
+			n++
+		}
+	}
+	return n
+}
+
+// fakeLifecycle 可配置耗时、错误与依赖的生命周期组件
+type fakeLifecycle struct {
+	title string
+	deps  []string
+	delay time.Duration
+	err   error
+	log   *eventLog
+}
+
+func (f *fakeLifecycle) Title() string {
+	return f.title
+}
+
+func (f *fakeLifecycle) DependsOn() []string {
+	return f.deps
+}
+
+func (f *fakeLifecycle) OnPrepareContext(ctx context.Context) error {
+	f.log.add("prepare:" + f.title)
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return f.err
+}
+
+func (f *fakeLifecycle) OnDestroy(ctx context.Context) error {
+	f.log.add("destroy:" + f.title)
+	return nil
+}
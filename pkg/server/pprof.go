@@ -1,8 +1,9 @@
 package server
 
 import (
-	"fmt"
+	"context"
 	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/logger"
 	"net/http"
 	_ "net/http/pprof"
 )
@@ -13,7 +14,8 @@ func NewPprofLifecycle() *PprofLifecycle {
 	return new(PprofLifecycle)
 }
 
-func (p *PprofLifecycle) OnPrepare() error {
+// Run 以Worker方式运行pprof服务，监听失败时由框架退避重启，停机时关闭服务
+func (p *PprofLifecycle) Run(ctx context.Context) error {
 	conf := config.GetWrapper("listeners.pprof")
 	enable := conf.GetBool("enable")
 	if !enable {
@@ -25,14 +27,19 @@ func (p *PprofLifecycle) OnPrepare() error {
 	}
 	addr := "localhost:" + port
 
+	srv := &http.Server{Addr: addr, Handler: http.DefaultServeMux}
+	errCh := make(chan error, 1)
 	go func() {
-		err := http.ListenAndServe(addr, nil)
-		if err != nil {
-			panic(err)
-		}
-		fmt.Printf("pprof listen on %s\n", addr)
+		errCh <- srv.ListenAndServe()
 	}()
-	return nil
+	logger.Infof("pprof listen on %s", addr)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return srv.Close()
+	}
 }
 
 func (p *PprofLifecycle) Title() string {
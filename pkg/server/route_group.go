@@ -0,0 +1,77 @@
+package server
+
+import (
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// RouteGroup 路由分组，分组内的路由共享路径前缀和中间件，支持嵌套分组
+type RouteGroup struct {
+	router     *Router
+	parent     *RouteGroup
+	prefix     string
+	middleware []echo.MiddlewareFunc
+}
+
+// Group 在默认监听器下创建路由分组，如：server.Group("/api/v1", auth).GET("/user", Query)
+func Group(prefix string, middleware ...echo.MiddlewareFunc) *RouteGroup {
+	return Listen(constant.DefaultListener).Group(prefix, middleware...)
+}
+
+// Group 在当前分组下创建子分组，子分组继承父分组的路径前缀和中间件
+func (g *RouteGroup) Group(prefix string, middleware ...echo.MiddlewareFunc) *RouteGroup {
+	return &RouteGroup{
+		router:     g.router,
+		parent:     g,
+		prefix:     prefix,
+		middleware: middleware,
+	}
+}
+
+// Add 注册分组路由，与RegisterRoute一样，重复注册（method+完整路径）会panic
+func (g *RouteGroup) Add(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
+	g.router.addRoute(g, method, path, handler, middleware...)
+}
+
+func (g *RouteGroup) GET(path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
+	g.Add(http.MethodGet, path, handler, middleware...)
+}
+
+func (g *RouteGroup) POST(path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
+	g.Add(http.MethodPost, path, handler, middleware...)
+}
+
+func (g *RouteGroup) PUT(path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
+	g.Add(http.MethodPut, path, handler, middleware...)
+}
+
+func (g *RouteGroup) DELETE(path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
+	g.Add(http.MethodDelete, path, handler, middleware...)
+}
+
+// fullPrefix 返回包含所有父分组前缀的完整路径前缀
+func (g *RouteGroup) fullPrefix() string {
+	if g.parent == nil {
+		return g.prefix
+	}
+	return g.parent.fullPrefix() + g.prefix
+}
+
+// mount 将分组挂载为echo.Group，父分组先于子分组挂载，同一分组只挂载一次
+func (g *RouteGroup) mount(e *echo.Echo, mounted map[*RouteGroup]*echo.Group) *echo.Group {
+	if eg, ok := mounted[g]; ok {
+		return eg
+	}
+	var eg *echo.Group
+	if g.parent == nil {
+		eg = e.Group(g.prefix, g.middleware...)
+	} else {
+		eg = g.parent.mount(e, mounted).Group(g.prefix, g.middleware...)
+	}
+	// echo.Group带中间件时会注册默认的404路由，这里替换为统一的响应格式
+	eg.RouteNotFound("", routeNotFound)
+	eg.RouteNotFound("/*", routeNotFound)
+	mounted[g] = eg
+	return eg
+}
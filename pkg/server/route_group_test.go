@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// trace 中间件将名称追加到响应头X-Trace，用于检查中间件的执行顺序
+func trace(name string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Add("X-Trace", name)
+			return next(c)
+		}
+	}
+}
+
+func TestRouteGroup(t *testing.T) {
+	ok := func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Path())
+	}
+	h := newRouteHandler(t, "group", func(r *Router) {
+		api := r.Group("/api", trace("api"))
+		v1 := api.Group("/v1", trace("v1"))
+		v1.GET("/users", ok, trace("route"))
+		v1.Group("/admin", trace("admin")).POST("/users", ok)
+		api.GET("/ping", ok)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		path   string
+		trace  []string
+	}{
+		{"nested prefix", http.MethodGet, "/api/v1/users", http.StatusOK, "/api/v1/users", []string{"api", "v1", "route"}},
+		{"deeper nesting", http.MethodPost, "/api/v1/admin/users", http.StatusOK, "/api/v1/admin/users", []string{"api", "v1", "admin"}},
+		{"parent group", http.MethodGet, "/api/ping", http.StatusOK, "/api/ping", []string{"api"}},
+		{"not found in nested group", http.MethodGet, "/api/v1/missing", http.StatusNotFound, "", []string{"api", "v1"}},
+		{"not found at group root", http.MethodGet, "/api", http.StatusNotFound, "", []string{"api"}},
+		{"not found outside groups", http.MethodGet, "/other", http.StatusNotFound, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h, tt.method, tt.target, "", nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if got := rec.Header().Values("X-Trace"); !equalStrings(got, tt.trace) {
+				t.Fatalf("middleware order = %v, want %v", got, tt.trace)
+			}
+			if tt.status == http.StatusOK {
+				if got := rec.Body.String(); got != tt.path {
+					t.Fatalf("matched route = %q, want %q", got, tt.path)
+				}
+				return
+			}
+			// 分组内的404与全局404使用统一的响应格式
+			var got handlerResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("404 body %q is not a unified result: %v", rec.Body.String(), err)
+			}
+			if got.Success || got.Msg != "api not found" || got.Tid == "" {
+				t.Fatalf("404 body = %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouteGroupDuplicateRoutePanics(t *testing.T) {
+	t.Cleanup(func() {
+		routers.Delete("duplicate")
+	})
+	handler := func(c echo.Context) error {
+		return nil
+	}
+	r := Listen("duplicate")
+	r.RegisterRoute(http.MethodGet, "/api/v1/users", handler)
+	r.Group("/api").Group("/v2").GET("/users", handler)
+
+	defer func() {
+		p := recover()
+		if p == nil {
+			t.Fatal("registering the same method and full path twice must panic")
+		}
+		if msg, _ := p.(string); !strings.Contains(msg, "GET:/api/v1/users already exists") {
+			t.Fatalf("panic = %v, want duplicate route message", p)
+		}
+	}()
+	r.Group("/api").Group("/v1").GET("/users", handler)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
 	"github.com/chnyangzhen/kago-fly/pkg/logger"
 )
 
@@ -12,22 +13,39 @@ func init() {
 func AddLifecycle(l Lifecycle) {
 	if v, ok := l.(Preparer); ok {
 		RegisterPrepare(v)
+	} else if v, ok := l.(PreparerContext); ok {
+		RegisterPrepareContext(v)
 	}
 
 	if v, ok := l.(After); ok {
 		RegisterStartedAfter(v)
+	} else if v, ok := l.(AfterContext); ok {
+		RegisterStartedAfterContext(v)
 	}
 
 	if v, ok := l.(Destroyer); ok {
 		RegisterDestroy(v)
 	}
+
+	if v, ok := l.(Worker); ok {
+		RegisterWorker(v)
+	}
+
+	if v, ok := l.(Checker); ok {
+		RegisterHealthCheck(v.Title(), v.HealthCheck)
+	}
 }
 
 func Run(banner string) error {
 	fmt.Println(banner)
-	server := NewServer()
-	server.StartGraceful()
-	return nil
+	if err := SortLifecycle(); err != nil {
+		return err
+	}
+	server, err := NewServer()
+	if err != nil {
+		return err
+	}
+	return server.StartGraceful()
 }
 
 // 初始化生命周期
@@ -35,6 +53,9 @@ func initLifecycle() {
 	logLifecycle := logger.NewLogLifecycle()
 	RegisterDestroy(logLifecycle)
 
+	viperLifecycle := config.NewViperLifecycle()
+	RegisterDestroy(viperLifecycle)
+
 	pprofLifecycle := NewPprofLifecycle()
-	RegisterPrepare(pprofLifecycle)
+	RegisterWorker(pprofLifecycle)
 }
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"github.com/chnyangzhen/kago-fly/pkg/logger"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	WorkerRunning  = "running"
+	WorkerBackoff  = "backoff"
+	WorkerFinished = "finished"
+	WorkerStopped  = "stopped"
+
+	defaultWorkerInitialBackoff = time.Second
+	defaultWorkerMaxBackoff     = time.Minute
+)
+
+var workers = make([]Worker, 0, 8)
+
+// Worker 受监管的后台任务，在监听器启动后运行，停机时取消ctx。
+// Run返回错误或panic时按指数退避重启，返回nil表示任务正常结束，不再重启
+type Worker interface {
+	Run(ctx context.Context) error
+	Title() string
+}
+
+// RegisterWorker 注册后台任务
+func RegisterWorker(worker Worker) {
+	register(worker)
+	workers = append(workers, worker)
+}
+
+// WorkerLifecycle 返回Worker列表的副本
+func WorkerLifecycle() []Worker {
+	dst := make([]Worker, len(workers))
+	copy(dst, workers)
+	return dst
+}
+
+// supervisor 负责Worker的启动、重启与状态上报
+type supervisor struct {
+	worker   Worker
+	mu       sync.RWMutex
+	state    string
+	restarts int
+	lastErr  error
+	done     chan struct{}
+}
+
+func newSupervisor(worker Worker) *supervisor {
+	return &supervisor{
+		worker: worker,
+		state:  WorkerRunning,
+		done:   make(chan struct{}),
+	}
+}
+
+func (w *supervisor) run(ctx context.Context) {
+	defer close(w.done)
+	title := w.worker.Title()
+	conf := config.GetWrapper(config.MakeKey(constant.LifecycleConfig, "worker"))
+	initial := conf.GetDuration("initial_backoff")
+	if initial <= 0 {
+		initial = defaultWorkerInitialBackoff
+	}
+	max := conf.GetDuration("max_backoff")
+	if max <= 0 {
+		max = defaultWorkerMaxBackoff
+	}
+
+	backoff := initial
+	for {
+		w.setState(WorkerRunning, nil)
+		registry.transition(title, StateReady, nil)
+		start := time.Now()
+		err := w.runOnce(ctx)
+		if ctx.Err() != nil {
+			w.setState(WorkerStopped, nil)
+			registry.transition(title, StateDestroyed, nil)
+			return
+		}
+		if err == nil {
+			logger.Infof("worker: %s finished.", title)
+			w.setState(WorkerFinished, nil)
+			return
+		}
+
+		// 稳定运行超过最大退避时间后，重新从初始退避时间开始计算
+		if time.Since(start) > max {
+			backoff = initial
+		}
+		w.setState(WorkerBackoff, err)
+		registry.transition(title, StateFailed, err)
+		logger.Errorw("worker exited with error, restarting", "worker", title, "error", err, "backoff", backoff.String())
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			w.setState(WorkerStopped, nil)
+			registry.transition(title, StateDestroyed, nil)
+			return
+		}
+		w.mu.Lock()
+		w.restarts++
+		w.mu.Unlock()
+		backoff *= 2
+		if backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// runOnce 执行一次Worker，panic转换为错误并打印堆栈
+func (w *supervisor) runOnce(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorw("worker panic", "worker", w.worker.Title(), "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return w.worker.Run(ctx)
+}
+
+func (w *supervisor) setState(state string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = state
+	if err != nil {
+		w.lastErr = err
+	}
+}
+
+// healthCheck Worker处于退避重启状态时视为不健康
+func (w *supervisor) healthCheck(ctx context.Context) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.state == WorkerBackoff {
+		return fmt.Errorf("worker %s restarting after error: %v, restarts: %d", w.worker.Title(), w.lastErr, w.restarts)
+	}
+	return nil
+}
+
+// startWorkers 启动所有Worker，并为每个Worker注册名为 worker:{title} 的健康检查
+func (s *Server) startWorkers() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancelWorkers = cancel
+	for _, worker := range WorkerLifecycle() {
+		sup := newSupervisor(worker)
+		s.supervisors = append(s.supervisors, sup)
+		RegisterHealthCheck("worker:"+worker.Title(), sup.healthCheck)
+		logger.Infof("worker: %s is starting.", worker.Title())
+		go sup.run(ctx)
+	}
+}
+
+// stopWorkers 取消所有Worker并等待退出，超过ctx截止时间后不再等待
+func (s *Server) stopWorkers(ctx context.Context) {
+	if s.cancelWorkers == nil {
+		return
+	}
+	s.cancelWorkers()
+	for _, sup := range s.supervisors {
+		select {
+		case <-sup.done:
+		case <-ctx.Done():
+			logger.Warnf("worker: %s did not stop before shutdown deadline", sup.worker.Title())
+		}
+	}
+}
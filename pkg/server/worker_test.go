@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/chnyangzhen/kago-fly/pkg/config"
+	"github.com/chnyangzhen/kago-fly/pkg/constant"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedWorker 按顺序执行预设的每次运行，最后一次之后阻塞到ctx取消
+type scriptedWorker struct {
+	title string
+	steps []func(ctx context.Context) error
+
+	mu     sync.Mutex
+	starts []time.Time
+	ends   []time.Time
+	runs   chan int
+}
+
+func newScriptedWorker(title string, steps ...func(ctx context.Context) error) *scriptedWorker {
+	return &scriptedWorker{title: title, steps: steps, runs: make(chan int, len(steps)+1)}
+}
+
+func (w *scriptedWorker) Title() string {
+	return w.title
+}
+
+func (w *scriptedWorker) Run(ctx context.Context) error {
+	w.mu.Lock()
+	n := len(w.starts)
+	w.starts = append(w.starts, time.Now())
+	w.mu.Unlock()
+	w.runs <- n
+	defer func() {
+		w.mu.Lock()
+		w.ends = append(w.ends, time.Now())
+		w.mu.Unlock()
+	}()
+	if n < len(w.steps) {
+		return w.steps[n](ctx)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// waitRuns 等待Worker第n次运行开始
+func (w *scriptedWorker) waitRuns(t *testing.T, n int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case run := <-w.runs:
+			if run+1 >= n {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("worker %s did not run %d times within %s", w.title, n, timeout)
+		}
+	}
+}
+
+// gaps 每次重启前的等待时间：上一次运行结束到下一次运行开始
+func (w *scriptedWorker) gaps() []time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	gaps := make([]time.Duration, 0, len(w.starts))
+	for i := 1; i < len(w.starts) && i <= len(w.ends); i++ {
+		gaps = append(gaps, w.starts[i].Sub(w.ends[i-1]))
+	}
+	return gaps
+}
+
+func fail(ctx context.Context) error {
+	return errors.New("boom")
+}
+
+// withBackoff 设置Worker的退避时间
+func withBackoff(t *testing.T, initial, max time.Duration) {
+	t.Helper()
+	conf := config.GetWrapper(config.MakeKey(constant.LifecycleConfig, "worker"))
+	conf.Set("initial_backoff", initial)
+	conf.Set("max_backoff", max)
+	t.Cleanup(func() {
+		conf.Set("initial_backoff", 0)
+		conf.Set("max_backoff", 0)
+	})
+}
+
+// startSupervisor 在后台运行Worker，测试结束时取消并等待退出
+func startSupervisor(t *testing.T, worker Worker) (*supervisor, context.CancelFunc) {
+	t.Helper()
+	sup := newSupervisor(worker)
+	ctx, cancel := context.WithCancel(context.Background())
+	go sup.run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		<-sup.done
+	})
+	return sup, cancel
+}
+
+func TestSupervisorBackoffGrowsToCap(t *testing.T) {
+	withBackoff(t, 20*time.Millisecond, 80*time.Millisecond)
+	worker := newScriptedWorker("backoff", fail, fail, fail, fail, fail)
+	startSupervisor(t, worker)
+	worker.waitRuns(t, 6, 3*time.Second)
+
+	gaps := worker.gaps()
+	want := []time.Duration{20, 40, 80, 80, 80}
+	for i, w := range want {
+		min := w * time.Millisecond
+		if gaps[i] < min {
+			t.Fatalf("backoff %d = %s, want at least %s (gaps %v)", i, gaps[i], min, gaps)
+		}
+		// 达到上限后不再翻倍
+		if gaps[i] >= 2*min {
+			t.Fatalf("backoff %d = %s, want less than %s (gaps %v)", i, gaps[i], 2*min, gaps)
+		}
+	}
+}
+
+func TestSupervisorBackoffResetsAfterHealthyRun(t *testing.T) {
+	withBackoff(t, 20*time.Millisecond, 80*time.Millisecond)
+	healthy := func(ctx context.Context) error {
+		time.Sleep(120 * time.Millisecond)
+		return errors.New("boom after healthy run")
+	}
+	worker := newScriptedWorker("reset", fail, fail, fail, healthy)
+	startSupervisor(t, worker)
+	worker.waitRuns(t, 5, 3*time.Second)
+
+	gaps := worker.gaps()
+	if gaps[2] < 80*time.Millisecond {
+		t.Fatalf("backoff before healthy run = %s, want at least 80ms (gaps %v)", gaps[2], gaps)
+	}
+	// 稳定运行超过最大退避时间后，从初始退避时间重新开始
+	if gaps[3] >= 40*time.Millisecond {
+		t.Fatalf("backoff after healthy run = %s, want reset to 20ms (gaps %v)", gaps[3], gaps)
+	}
+}
+
+func TestSupervisorRestartsAfterPanic(t *testing.T) {
+	withBackoff(t, 10*time.Millisecond, 10*time.Millisecond)
+	worker := newScriptedWorker("panic", func(ctx context.Context) error {
+		panic("worker panic")
+	})
+	sup, _ := startSupervisor(t, worker)
+	worker.waitRuns(t, 2, 3*time.Second)
+
+	sup.mu.RLock()
+	defer sup.mu.RUnlock()
+	if sup.restarts != 1 {
+		t.Fatalf("restarts = %d, want 1", sup.restarts)
+	}
+	if sup.lastErr == nil || !strings.Contains(sup.lastErr.Error(), "panic: worker panic") {
+		t.Fatalf("last error = %v, want recovered panic", sup.lastErr)
+	}
+}
+
+func TestSupervisorStopsOnCancel(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		steps []func(ctx context.Context) error
+	}{
+		{"while running", nil},
+		{"while backing off", []func(ctx context.Context) error{fail}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			withBackoff(t, time.Minute, time.Minute)
+			worker := newScriptedWorker("cancel", tt.steps...)
+			sup, cancel := startSupervisor(t, worker)
+			worker.waitRuns(t, 1, time.Second)
+			if len(tt.steps) > 0 {
+				waitWorkerState(t, sup, WorkerBackoff)
+			}
+
+			cancel()
+			select {
+			case <-sup.done:
+			case <-time.After(time.Second):
+				t.Fatal("supervisor did not stop after cancel")
+			}
+			if got := len(worker.runs); got != 0 {
+				t.Fatalf("worker restarted %d times after cancel", got)
+			}
+			sup.mu.RLock()
+			defer sup.mu.RUnlock()
+			if sup.state != WorkerStopped || sup.restarts != 0 {
+				t.Fatalf("state = %s, restarts = %d, want %s without restart", sup.state, sup.restarts, WorkerStopped)
+			}
+		})
+	}
+}
+
+func TestWorkerHealthCheckFailsWhileBackingOff(t *testing.T) {
+	withBackoff(t, 200*time.Millisecond, 200*time.Millisecond)
+	worker := newScriptedWorker("health", fail)
+	saved := workers
+	workers = []Worker{worker}
+	s := newTestServer()
+	t.Cleanup(func() {
+		s.stopWorkers(context.Background())
+		workers = saved
+		healthChecks.Delete("worker:health")
+	})
+	s.startWorkers()
+
+	value, ok := healthChecks.Load("worker:health")
+	if !ok {
+		t.Fatal("worker:health check is not registered")
+	}
+	check := value.(HealthChecker)
+	waitWorkerState(t, s.supervisors[0], WorkerBackoff)
+	if err := check(context.Background()); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("health check while backing off = %v, want worker error", err)
+	}
+
+	// 重启后恢复健康
+	worker.waitRuns(t, 2, time.Second)
+	waitWorkerState(t, s.supervisors[0], WorkerRunning)
+	if err := check(context.Background()); err != nil {
+		t.Fatalf("health check after restart = %v", err)
+	}
+}
+
+func waitWorkerState(t *testing.T, sup *supervisor, state string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		sup.mu.RLock()
+		got := sup.state
+		sup.mu.RUnlock()
+		if got == state {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("worker did not reach state %s", state)
+}
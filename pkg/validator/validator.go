@@ -13,12 +13,20 @@ type DataValidator struct {
 }
 
 func (c *DataValidator) Validate(i interface{}) error {
+	if err := c.ValidateStruct(i); err != nil {
+		panic(err)
+	}
+	return nil
+}
+
+// ValidateStruct 校验结构体，校验失败时返回第一个翻译后的参数错误，不会panic
+func (c *DataValidator) ValidateStruct(i interface{}) error {
 	c.lazyInit()
 	err := c.validate.Struct(i)
 	if err != nil {
 		translator, _ := helper.FindTranslator("en")
 		for _, err := range err.(validator.ValidationErrors) {
-			panic(response.NewParamError(err.Translate(translator)))
+			return response.NewParamError(err.Translate(translator))
 		}
 	}
 	return nil
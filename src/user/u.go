@@ -12,7 +12,7 @@ import (
 
 func init() {
 	server.RegisterRoute("GET", "/user", Query)
-	server.RegisterRoute("POST", "/user", Post)
+	server.RegisterRoute("POST", "/user", server.Handle(Post))
 }
 
 type User struct {
@@ -20,24 +20,18 @@ type User struct {
 	Age  int    `validate:"gte=1,lte=130" json:"age"`
 }
 
-func Post(ctx echo.Context) error {
-	user := new(User)
-	ctx.Bind(user)
-	ctx.Validate(user)
+func Post(ctx context.Context, user *User) (*User, error) {
+	// Handle已完成参数绑定与校验，ctx中携带echo设置的tid
+	logger.Trace(ctx).Info("yyyyyyyyyyyyyyyyyy")
 
-	// 获取echo中设置的tid
-	webTid := tidctx.WebTid(ctx)
-	logger.TraceId(webTid).Info("yyyyyyyyyyyyyyyyyy")
-
-	// web调用下游服务转换context
-	web := tidctx.WrapWebCtx(ctx)
-	t(web)
+	// web调用下游服务直接传递context
+	t(ctx)
 
 	// 非web调用下游服务转换context
 	msgId := helper.Uuid()
 	nonWeb := tidctx.InitTidCtx(msgId)
 	t(nonWeb)
-	return server.WriteSuccess(ctx, user)
+	return user, nil
 }
 
 func Query(ctx echo.Context) error {